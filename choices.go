@@ -0,0 +1,24 @@
+package goptions
+
+var (
+	choicesMap = map[string]func() []string{}
+)
+
+// RegisterChoices makes a provider of choices available to the
+// `choices-func` option under the given name. The provider is called each
+// time the choices are needed, so the accepted values can change at
+// runtime. Providers have to be registered before the FlagSets using them
+// are created.
+func RegisterChoices(name string, fn func() []string) {
+	choicesMap[name] = fn
+}
+
+// Choices returns the values accepted by the flag as given by the
+// `choices` or `choices-func` option, or nil if the flag accepts any value.
+func (f *Flag) Choices() []string {
+	if fn, ok := f.optionMeta["choices_func"].(func() []string); ok {
+		return fn()
+	}
+	choices, _ := f.optionMeta["choices"].([]string)
+	return choices
+}
@@ -0,0 +1,40 @@
+package goptions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChoicesFunc(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Region string `goptions:"--region, choices-func='regions'"`
+	}
+
+	regions := []string{"eu-west", "us-east"}
+	RegisterChoices("regions", func() []string {
+		return regions
+	})
+	defer delete(choicesMap, "regions")
+
+	args = []string{"--region", "us-east"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Region != "us-east" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	regions = append(regions, "ap-south")
+	options.Region = ""
+	args = []string{"--region", "mars"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || !strings.Contains(err.Error(), "must be one of: eu-west, us-east, ap-south") {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
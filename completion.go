@@ -0,0 +1,99 @@
+package goptions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var _NON_IDENTIFIER_REGEXP = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// WriteBashCompletion writes a bash completion script for the FlagSet to
+// the given writer. The script completes the flags and verbs of the
+// innermost verb found on the command line and the choices of the flag
+// preceding the cursor. It is meant to be emitted when Parse() returns
+// ErrCompletionRequest and to be sourced by the shell, e.g.
+// `source <(tool --generate-bash-completion)`.
+func (fs *FlagSet) WriteBashCompletion(w io.Writer) error {
+	name := fs.ProgramName()
+	function := "_" + _NON_IDENTIFIER_REGEXP.ReplaceAllString(name, "_")
+
+	var verbs, words, choices bytes.Buffer
+	writeBashCompletion(&verbs, &words, &choices, fs, "")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s() {\n", function)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" verb=\"\" words=\"\" i\n")
+	b.WriteString("\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	b.WriteString("\t\tcase \"$verb ${COMP_WORDS[i]}\" in\n")
+	b.Write(verbs.Bytes())
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\tdone\n")
+	b.WriteString("\tcase \"$verb $prev\" in\n")
+	b.Write(choices.Bytes())
+	b.WriteString("\tesac\n")
+	b.WriteString("\tcase \"$verb\" in\n")
+	b.Write(words.Bytes())
+	b.WriteString("\tesac\n")
+	b.WriteString("\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", function, bashQuote(name))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeBashCompletion(verbs, words, choices *bytes.Buffer, fs *FlagSet, path string) {
+	candidates := make([]string, 0, 2*len(fs.Flags)+len(fs.Verbs))
+	for _, f := range fs.Flags {
+		names := make([]string, 0, 2)
+		if f.Short != "" {
+			names = append(names, "-"+f.Short)
+		}
+		if f.Long != "" {
+			names = append(names, "--"+f.Long)
+		}
+		for _, alias := range f.aliases {
+			names = append(names, "--"+alias)
+		}
+		for _, negated := range f.NegatedLongs() {
+			names = append(names, "--"+negated)
+		}
+		candidates = append(candidates, names...)
+		if values := f.Choices(); len(values) > 0 && f.NeedsExtraValue() {
+			patterns := make([]string, 0, len(names))
+			for _, name := range names {
+				patterns = append(patterns, bashQuote(path+" "+name))
+			}
+			fmt.Fprintf(choices, "\t%s) COMPREPLY=($(compgen -W %s -- \"$cur\")); return ;;\n",
+				strings.Join(patterns, "|"), bashQuote(strings.Join(values, " ")))
+		}
+	}
+
+	names := make([]string, 0, len(fs.Verbs))
+	for name := range fs.Verbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	candidates = append(candidates, names...)
+
+	fmt.Fprintf(words, "\t%s) words=%s ;;\n", bashQuote(path), bashQuote(strings.Join(candidates, " ")))
+
+	for _, name := range names {
+		verb := fs.Verbs[name]
+		verbPath := strings.TrimPrefix(path+" "+name, " ")
+		patterns := []string{bashQuote(path + " " + name)}
+		for _, alias := range verb.Aliases {
+			patterns = append(patterns, bashQuote(path+" "+alias))
+		}
+		fmt.Fprintf(verbs, "\t\t%s) verb=%s ;;\n", strings.Join(patterns, "|"), bashQuote(verbPath))
+		writeBashCompletion(verbs, words, choices, verb, verbPath)
+	}
+}
+
+// bashQuote quotes s for use as a single word in a bash script.
+func bashQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,41 @@
+package goptions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteBashCompletion(t *testing.T) {
+	var options struct {
+		Completion Completion `goptions:"--generate-bash-completion"`
+		Verbose    bool       `goptions:"-v, --verbose"`
+		Format     string     `goptions:"--format, choices='json,text'"`
+		Verbs
+		Deploy struct {
+			Server string `goptions:"-s, --server, obligatory"`
+		} `goptions:"deploy, d"`
+	}
+	fs := NewFlagSet("/usr/bin/my-tool", &options)
+	if err := fs.Parse([]string{"--generate-bash-completion"}); err != ErrCompletionRequest {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := fs.WriteBashCompletion(&buf); err != nil {
+		t.Fatalf("Writing completion failed: %s", err)
+	}
+	script := buf.String()
+	for _, expected := range []string{
+		"_my_tool() {\n",
+		"\t\t' deploy'|' d') verb='deploy' ;;\n",
+		"\t' --format') COMPREPLY=($(compgen -W 'json text' -- \"$cur\")); return ;;\n",
+		"\t'') words='--generate-bash-completion -v --verbose --format deploy' ;;\n",
+		"\t'deploy') words='-s --server' ;;\n",
+		"complete -F _my_tool 'my-tool'\n",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Fatalf("Unexpected completion script, missing %q:\n%s", expected, script)
+		}
+	}
+}
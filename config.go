@@ -0,0 +1,161 @@
+package goptions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// LoadJSON sets the flags of the FlagSet from a JSON object. The keys of
+// the object are the long names of the flags. Arrays set each of their
+// elements for slice flags and objects set each of their entries for map
+// flags. Any other object whose key is the name of a verb sets the flags of
+// that verb. Null values and nested arrays or objects are rejected. Flags
+// which have been set from the command line or the environment keep their
+// value.
+func (fs *FlagSet) LoadJSON(r io.Reader) error {
+	var config map[string]interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	err := dec.Decode(&config)
+	if err != nil {
+		return err
+	}
+	return fs.loadConfig(config)
+}
+
+// MarshalJSON renders the current values of the flags with long names in
+// the format read by LoadJSON(), the flags of each verb as a nested object.
+// The values of secret flags are redacted.
+func (fs *FlagSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fs.jsonValues())
+}
+
+func (fs *FlagSet) jsonValues() map[string]interface{} {
+	r := make(map[string]interface{})
+	for _, f := range fs.Flags {
+		if f.Long == "" || f.isMarker() {
+			continue
+		}
+		if f.Secret {
+			r[f.Long] = "****"
+		} else {
+			r[f.Long] = jsonValue(f.value)
+		}
+	}
+	for name, verb := range fs.Verbs {
+		r[name] = verb.jsonValues()
+	}
+	return r
+}
+
+// jsonValue converts v into a value for encoding/json. Values which are
+// not booleans or numbers are rendered as strings, like in the JSONSchema().
+func jsonValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Slice:
+		r := make([]interface{}, v.Len())
+		for i := range r {
+			r[i] = jsonValue(v.Index(i))
+		}
+		return r
+	case reflect.Map:
+		r := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			r[fmt.Sprint(key.Interface())] = jsonValue(v.MapIndex(key))
+		}
+		return r
+	}
+	switch jsonSchemaType(v.Type()) {
+	case "boolean", "integer", "number":
+		return v.Interface()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func (fs *FlagSet) loadConfig(config map[string]interface{}) error {
+	for key, value := range config {
+		f, isFlag := fs.longMap[key]
+		isMap := isFlag && f.value.Kind() == reflect.Map
+		if verbConfig, ok := value.(map[string]interface{}); ok && !isMap {
+			verb, ok := fs.verbByName(key)
+			if !ok {
+				return fmt.Errorf("Unknown verb %s in config", key)
+			}
+			err := verb.loadConfig(verbConfig)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !isFlag || f.Long != key {
+			return fmt.Errorf("Unknown flag --%s in config", key)
+		}
+		values, err := configValues(value)
+		if err != nil {
+			return fmt.Errorf("Invalid value in config for %s: %s", f.Name(), err)
+		}
+		for _, v := range values {
+			err := f.setFromSource(v, SourceConfig)
+			if err != nil {
+				return fmt.Errorf("Invalid value in config for %s: %s", f.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// configValues converts a JSON value into the values to set for a flag:
+// the elements of an array, the key=value entries of an object sorted by
+// key or the scalar itself.
+func configValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		r := make([]string, 0, len(v))
+		for _, elem := range v {
+			s, err := configScalar(elem)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, s)
+		}
+		return r, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		r := make([]string, 0, len(v))
+		for _, key := range keys {
+			s, err := configScalar(v[key])
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, key+"="+s)
+		}
+		return r, nil
+	}
+	s, err := configScalar(value)
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+func configScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("null is not a valid value")
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("nested arrays and objects are not supported")
+}
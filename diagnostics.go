@@ -0,0 +1,174 @@
+package goptions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic describes a problem found by ParseDiagnostics().
+type Diagnostic struct {
+	// Position is the index of the offending argument in the arguments
+	// passed to ParseDiagnostics() or -1 if the problem is not tied to a
+	// single argument (e.g. a missing obligatory flag).
+	Position int
+	Severity Severity
+	Message  string
+	// Suggestions are possible replacements of the offending argument
+	// (e.g. the names of similar flags for an unknown flag).
+	Suggestions []string
+}
+
+// ErrTrailingArguments is returned by Parse() if arguments are left over
+// after the flags, verbs and positional arguments of a FlagSet without a
+// Remainder have been processed.
+type ErrTrailingArguments struct {
+	// FlagSet which has been parsed when the arguments were left over
+	FlagSet *FlagSet
+	Args    []string
+	// Position is the index of the first of the Args in the arguments of
+	// the root FlagSet or -1 if it is unknown.
+	Position int
+}
+
+func (e *ErrTrailingArguments) Error() string {
+	return fmt.Sprintf("Invalid trailing arguments: %v", e.Args)
+}
+
+// ParseDiagnostics works like Parse() but additionally reports all problems
+// found in args as diagnostics, e.g. for editor integrations. Errors are
+// collected as if CollectErrors was set, the use of deprecated flags is
+// reported as a warning and unknown flags come with suggestions of similar
+// flags. The returned error is the one Parse() would have returned.
+//
+// Only deprecated flags given by name on the command line are reported, not
+// positional arguments or values from the environment or a config file.
+// Suggestions only name the flags of the FlagSet the unknown flag was given
+// to, not the global flags of its parents. If arguments have been passed
+// through, the Position of the trailing arguments is -1.
+func (fs *FlagSet) ParseDiagnostics(args []string) ([]Diagnostic, error) {
+	root := fs.root()
+	collect := root.CollectErrors
+	root.CollectErrors = true
+	root.diagnosing = true
+	err := fs.Parse(args)
+	root.CollectErrors = collect
+	root.diagnosing = false
+
+	diagnostics := root.warnings
+	root.warnings = nil
+	if isMarkerRequest(err) {
+		return diagnostics, err
+	}
+	errs, ok := err.(Errors)
+	if !ok && err != nil {
+		errs = Errors{err}
+	}
+	for _, e := range errs {
+		diagnostics = append(diagnostics, errorDiagnostics(e)...)
+	}
+	return diagnostics, err
+}
+
+func errorDiagnostics(err error) []Diagnostic {
+	e, ok := err.(*ErrTrailingArguments)
+	if !ok || e.Position < 0 {
+		return []Diagnostic{{Position: -1, Severity: SeverityError, Message: err.Error()}}
+	}
+	r := make([]Diagnostic, 0, len(e.Args))
+	for i, arg := range e.Args {
+		d := Diagnostic{Position: e.Position + i, Severity: SeverityError}
+		if isLong(arg) || isShort(arg) {
+			d.Message = fmt.Sprintf("Unknown flag %s", arg)
+			d.Suggestions = e.FlagSet.similarFlags(arg)
+		} else {
+			d.Message = fmt.Sprintf("Invalid trailing argument %s", arg)
+		}
+		r = append(r, d)
+	}
+	return r
+}
+
+// warn records a warning if ParseDiagnostics() is running.
+func (fs *FlagSet) warn(position int, message string) {
+	r := fs.root()
+	if !r.diagnosing {
+		return
+	}
+	r.warnings = append(r.warnings, Diagnostic{Position: position, Severity: SeverityWarning, Message: message})
+}
+
+// similarFlags returns the names of the flags of the FlagSet which are at
+// most two edits away from the flag given in arg, closest first.
+func (fs *FlagSet) similarFlags(arg string) []string {
+	name := arg
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+	distances := make(map[string]int)
+	for _, f := range fs.Flags {
+		names := make([]string, 0, 2)
+		if f.Short != "" {
+			names = append(names, "-"+f.Short)
+		}
+		if f.Long != "" {
+			names = append(names, "--"+f.Long)
+		}
+		for _, alias := range f.aliases {
+			names = append(names, "--"+alias)
+		}
+		for _, candidate := range names {
+			if d := editDistance(name, candidate); d <= 2 && d < len(candidate)-1 {
+				distances[candidate] = d
+			}
+		}
+	}
+	r := make([]string, 0, len(distances))
+	for candidate := range distances {
+		r = append(r, candidate)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if distances[r[i]] != distances[r[j]] {
+			return distances[r[i]] < distances[r[j]]
+		}
+		return r[i] < r[j]
+	})
+	return r
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cur := row[j]
+			if a[i-1] == b[j-1] {
+				row[j] = prev
+			} else {
+				row[j] = 1 + min(prev, row[j], row[j-1])
+			}
+			prev = cur
+		}
+	}
+	return row[len(b)]
+}
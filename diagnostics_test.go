@@ -0,0 +1,71 @@
+package goptions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnostics_Deprecated(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Name  string `goptions:"--name"`
+		Quiet bool   `goptions:"-q, --quiet, deprecated='use --verbosity=0'"`
+	}
+	args = []string{"--name", "x", "--quiet"}
+	fs = NewFlagSet("goptions", &options)
+	diagnostics, err := fs.ParseDiagnostics(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Quiet || options.Name != "x" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+	expected := []Diagnostic{
+		{Position: 2, Severity: SeverityWarning, Message: "--quiet is deprecated: use --verbosity=0"},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Fatalf("Unexpected diagnostics: %#v", diagnostics)
+	}
+
+	fs.LastWins = true
+	for i := 0; i < 2; i++ {
+		err = fs.Parse(args)
+		if err != nil {
+			t.Fatalf("Parsing failed: %s", err)
+		}
+	}
+	if len(fs.warnings) != 0 {
+		t.Fatalf("Unexpected warnings recorded by Parse(): %#v", fs.warnings)
+	}
+}
+
+func TestParseDiagnostics_UnknownFlag(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool `goptions:"-v, --verbose"`
+		Verbs
+		Build struct {
+			Output string `goptions:"-o, --output"`
+		} `goptions:"build"`
+	}
+	args = []string{"-v", "build", "--outptu", "x"}
+	fs = NewFlagSet("goptions", &options)
+	diagnostics, err := fs.ParseDiagnostics(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+	if fs.CollectErrors {
+		t.Fatalf("CollectErrors has not been restored")
+	}
+	expected := []Diagnostic{
+		{Position: 2, Severity: SeverityError, Message: "Unknown flag --outptu", Suggestions: []string{"--output"}},
+		{Position: 3, Severity: SeverityError, Message: "Invalid trailing argument x"},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Fatalf("Unexpected diagnostics: %#v", diagnostics)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package goptions
+
+// setEcho cannot control the echo of the terminal on this system, so the
+// input of secret flags is echoed.
+func setEcho(on bool) bool {
+	return false
+}
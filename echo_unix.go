@@ -0,0 +1,24 @@
+//go:build unix
+
+package goptions
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setEcho turns the echo of the terminal on stdin on or off using stty and
+// reports whether it succeeded.
+func setEcho(on bool) bool {
+	stty, err := exec.LookPath("stty")
+	if err != nil {
+		return false
+	}
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command(stty, mode)
+	cmd.Stdin = os.Stdin
+	return cmd.Run() == nil
+}
@@ -0,0 +1,65 @@
+package goptions
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LoadEnvFile sets the flags of the FlagSet and its verbs from a
+// dotenv-style file of KEY=VALUE lines. A line is applied to the flag whose
+// environment variable, given by the `env` option or derived from the
+// EnvPrefix, is KEY. Other keys, blank lines and lines starting with "#"
+// are ignored. Values may be quoted. Flags which have been set from the
+// command line keep their value and the real environment takes precedence
+// when Parse() is called.
+func (fs *FlagSet) LoadEnvFile(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			return fmt.Errorf("Invalid line %d in env file: %s", n, line)
+		}
+		key := strings.TrimSpace(line[:idx])
+		value := unquoteEnvValue(strings.TrimSpace(line[idx+1:]))
+		err := fs.setEnvValue(key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (fs *FlagSet) setEnvValue(key, value string) error {
+	for _, f := range fs.Flags {
+		if name, ok := f.envName(); !ok || name != key {
+			continue
+		}
+		err := f.setFromSource(value, SourceEnv)
+		if err != nil {
+			return fmt.Errorf("Invalid value of %s for %s: %s", key, f.Name(), err)
+		}
+	}
+	for _, verb := range fs.Verbs {
+		err := verb.setEnvValue(key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unquoteEnvValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
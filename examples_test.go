@@ -27,9 +27,10 @@ func ExampleFlagSet_PrintHelp() {
 
 	args := []string{"--help"}
 	fs := NewFlagSet("goptions", &options)
+	fs.HelpWidth = 80 // Independent of the COLUMNS of the terminal
 	err := fs.Parse(args)
 	if err == ErrHelpRequest {
-		fs.PrintHelp(os.Stderr)
+		fs.PrintHelp(os.Stdout)
 		return
 	} else if err != nil {
 		fmt.Printf("Failure: %s", err)
@@ -51,4 +52,9 @@ func ExampleFlagSet_PrintHelp() {
 	//     execute:
 	//             --command Command to exectute (*)
 	//             --script  Script to execture
+	//
+	// (*) marks obligatory flags
+	//
+	// Constraints:
+	//     execute: Exactly one of --command, --script
 }
@@ -1,9 +1,12 @@
 package goptions
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // Flag represents a single flag of a FlagSet.
@@ -12,17 +15,26 @@ type Flag struct {
 	Long         string
 	MutexGroups  []string
 	Description  string
+	ErrorMessage string
 	Obligatory   bool
+	Secret       bool
 	WasSpecified bool
 	value        reflect.Value
 	optionMeta   map[string]interface{}
 	DefaultValue interface{}
+	source       Source
+	flagSet      *FlagSet
+	index        int
+	aliases      []string
 }
 
 // Return the name of the flag preceding the right amount of dashes.
 // The long name is preferred. If no name has been specified, "<unspecified>"
 // will be returned.
 func (f *Flag) Name() string {
+	if f.isPositional() {
+		return f.Metavar()
+	}
 	if len(f.Long) > 0 {
 		return "--" + f.Long
 	}
@@ -32,58 +44,406 @@ func (f *Flag) Name() string {
 	return "<unspecified>"
 }
 
+// Default returns the value shown as the flag's default. Unless the flag
+// has been set by the user, this is the current value of the flag, so
+// fields set by the caller after NewFlagSet() are shown as well. A zero
+// value without a `default` option is no default and returned as nil. The
+// default of a secret flag is redacted.
+func (f *Flag) Default() interface{} {
+	d := f.value.Interface()
+	if f.isSet() {
+		d = f.DefaultValue
+	} else if _, ok := f.interpolatedDefault(); ok && f.source == SourceUnset {
+		d = f.DefaultValue
+	}
+	if _, ok := f.optionMeta["default"]; !ok && (d == nil || reflect.ValueOf(d).IsZero()) {
+		return nil
+	}
+	if f.Secret && d != nil && !reflect.ValueOf(d).IsZero() {
+		return "****"
+	}
+	return d
+}
+
+// Deprecated returns the deprecation notice of the flag given with the
+// `deprecated` option, or an empty string.
+func (f *Flag) Deprecated() string {
+	deprecated, _ := f.optionMeta["deprecated"].(string)
+	return deprecated
+}
+
+// Example returns the example usage of the flag given with the `example`
+// option, or an empty string.
+func (f *Flag) Example() string {
+	example, _ := f.optionMeta["example"].(string)
+	return example
+}
+
 // NeedsExtraValue returns true if the flag expects a separate value.
 func (f *Flag) NeedsExtraValue() bool {
 	// Explicit over implicit
 	if f.value.Type() == reflect.TypeOf(new(bool)).Elem() {
 		return false
 	}
-	if _, ok := f.value.Interface().(Help); ok {
+	if f.isMarker() {
+		return false
+	}
+	if _, ok := f.value.Interface().(Counter); ok {
 		return false
 	}
 	return true
 }
 
+// isMarker returns true if specifying the flag short-circuits Parse(),
+// like a Help flag.
+func (f *Flag) isMarker() bool {
+	_, ok := markerErrors[f.value.Type()]
+	return ok
+}
+
 // IsMulti returns true if the flag can be specified multiple times.
 func (f *Flag) IsMulti() bool {
-	if f.value.Kind() == reflect.Slice {
+	if f.value.Kind() == reflect.Slice || f.value.Kind() == reflect.Map {
+		return true
+	}
+	if _, ok := f.value.Interface().(Counter); ok {
+		return true
+	}
+	return false
+}
+
+// IsAccumulate returns true if every occurrence of the flag contributes to
+// its value, i.e. it is a Counter or an int with the `accumulate` or
+// `reduce` option.
+func (f *Flag) IsAccumulate() bool {
+	if _, ok := f.value.Interface().(Counter); ok {
+		return true
+	}
+	return f.accumulates()
+}
+
+func (f *Flag) isPositional() bool {
+	positional, _ := f.optionMeta["positional"].(bool)
+	return positional
+}
+
+// accumulates returns true if the values of multiple occurrences of the
+// flag are combined by a reducer.
+func (f *Flag) accumulates() bool {
+	_, ok := f.optionMeta["reduce"].(reducer)
+	return ok
+}
+
+// overrides returns true if the flag can be specified multiple times with
+// the last value winning.
+func (f *Flag) overrides() bool {
+	if override, _ := f.optionMeta["override"].(bool); override {
 		return true
 	}
+	return f.flagSet != nil && f.flagSet.root().LastWins
+}
+
+// isObligatory returns true if the flag must be specified on its own, i.e.
+// it is obligatory and not part of a MutexGroup or it is obligatory for
+// one of the selected verbs.
+func (f *Flag) isObligatory(selected ...*FlagSet) bool {
+	if f.Obligatory && len(f.MutexGroups) == 0 {
+		return true
+	}
+	verbs, _ := f.optionMeta["obligatory_for"].([]string)
+	for _, name := range verbs {
+		for _, verb := range selected {
+			if verb.Name == name {
+				return true
+			}
+		}
+	}
 	return false
 }
 
+// checkObligatoryIf returns an error if the flag has not been set although
+// one of the conditions of its `obligatory-if` option holds.
+func (f *Flag) checkObligatoryIf() error {
+	conditions, _ := f.optionMeta["obligatory_if"].([]condition)
+	if len(conditions) == 0 || f.isSet() {
+		return nil
+	}
+	for _, c := range conditions {
+		ref := f.flagSet.lookupInterpolated(c.name)
+		if ref == nil {
+			return fmt.Errorf("Unknown flag %s in condition of %s", c.name, f.Name())
+		}
+		if fmt.Sprint(ref.value.Interface()) == c.value {
+			return f.missingError(fmt.Errorf("%s must be specified if %s is %s", f.Name(), ref.Name(), c.value))
+		}
+	}
+	return nil
+}
+
+// String returns the current value of the flag as a string. The value of a
+// secret flag is redacted.
+func (f *Flag) String() string {
+	if f.Secret {
+		return "****"
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+// missingError returns the flag's ErrorMessage as an error or err if no
+// ErrorMessage has been set.
+func (f *Flag) missingError(err error) error {
+	if len(f.ErrorMessage) > 0 {
+		return errors.New(f.ErrorMessage)
+	}
+	return err
+}
+
 func isShort(arg string) bool {
-	return strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--")
+	return len(arg) > 1 && strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--")
+}
+
+// shortName returns the first short flag of arg without the leading dash.
+func shortName(arg string) string {
+	_, size := utf8.DecodeRuneInString(arg[1:])
+	return arg[1 : 1+size]
+}
+
+// isCluster returns true if arg is a cluster of several short flags.
+func isCluster(arg string) bool {
+	return isShort(arg) && len(arg) > 1+len(shortName(arg)) && !isShortEquals(arg)
+}
+
+// isShortEquals returns true if arg is a short flag using the equals
+// notation (e.g. `-n=5`).
+func isShortEquals(arg string) bool {
+	return isShort(arg) && strings.HasPrefix(arg[1+len(shortName(arg)):], "=")
 }
 
 func isLong(arg string) bool {
-	return strings.HasPrefix(arg, "--")
+	return len(arg) > 2 && strings.HasPrefix(arg, "--")
+}
+
+// longName returns the name of a long flag without the leading dashes and
+// without a value given in the equals notation.
+func longName(arg string) string {
+	name := arg[2:]
+	if idx := strings.Index(name, "="); idx >= 0 {
+		name = name[:idx]
+	}
+	return name
+}
+
+// sortName returns the name the flag is sorted by in the help.
+func (f *Flag) sortName() string {
+	if f.Long != "" {
+		return f.Long
+	}
+	return f.Short
+}
+
+// NegatedLongs returns the long names which clear a negatable
+// boolean flag. If the flag is not negatable, nil is returned.
+func (f *Flag) NegatedLongs() []string {
+	prefixes, _ := f.optionMeta["negate_prefixes"].([]string)
+	suffix, hasSuffix := f.optionMeta["off_suffix"].(string)
+	if len(f.Long) == 0 || (len(prefixes) == 0 && !hasSuffix) {
+		return nil
+	}
+	r := make([]string, 0, len(prefixes)+1)
+	for _, prefix := range prefixes {
+		r = append(r, prefix+f.Long)
+	}
+	if hasSuffix {
+		r = append(r, f.Long+suffix)
+	}
+	return r
 }
 
+func (f *Flag) isNegation(arg string) bool {
+	if !isLong(arg) {
+		return false
+	}
+	for _, name := range f.NegatedLongs() {
+		if longName(arg) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Flag) isAlias(name string) bool {
+	for _, alias := range f.aliases {
+		if name == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// Handles returns true if arg is a reference to this flag.
 func (f *Flag) Handles(arg string) bool {
-	return (isShort(arg) && arg[1:2] == f.Short) ||
-		(isLong(arg) && arg[2:] == f.Long)
+	return (isShort(arg) && shortName(arg) == f.Short) ||
+		(isLong(arg) && longName(arg) == f.Long) ||
+		(isLong(arg) && f.isAlias(longName(arg))) ||
+		f.isNegation(arg)
 
 }
 
 func (f *Flag) Parse(args []string) ([]string, error) {
 	param, value := args[0], ""
-	if f.NeedsExtraValue() &&
-		(len(args) < 2 || (isShort(param) && len(param) > 2)) {
-		return args, fmt.Errorf("Flag %s needs an argument", f.Name())
+	hasValue := false
+	if isLong(param) && len(longName(param)) < len(param)-2 {
+		// Equals notation
+		value = param[len(longName(param))+3:]
+		param = param[:len(longName(param))+2]
+		hasValue = true
+	} else if isShortEquals(param) {
+		value = param[2+len(shortName(param)):]
+		param = param[:1+len(shortName(param))]
+		hasValue = true
+		if value == "" && f.NeedsExtraValue() {
+			return args, f.missingError(fmt.Errorf("Flag %s needs an argument", f.Name()))
+		}
+	}
+	if f.NeedsExtraValue() && !hasValue &&
+		(len(args) < 2 || isCluster(param)) {
+		return args, f.missingError(fmt.Errorf("Flag %s needs an argument", f.Name()))
 	}
-	if f.WasSpecified && !f.IsMulti() {
+	if f.WasSpecified && !f.IsMulti() && !f.overrides() && !f.accumulates() {
 		return args, fmt.Errorf("Flag %s can only be specified once", f.Name())
 	}
-	if isShort(param) && len(param) > 2 {
+	if replace, _ := f.optionMeta["replace_on_set"].(bool); replace && !f.WasSpecified {
+		// Drop the elements seeded by a default or a config file
+		f.value.Set(reflect.Zero(f.value.Type()))
+	}
+	if max, ok := f.optionMeta["max"].(int); ok && f.value.Len() >= max {
+		return args, fmt.Errorf("Flag %s can be specified at most %d times", f.Name(), max)
+	}
+	if hasValue && f.isNegation(param) {
+		return args, fmt.Errorf("Flag %s does not take a value", param)
+	}
+	if greedy, _ := f.optionMeta["greedy"].(bool); greedy && !isCluster(param) {
+		values := args[1:]
+		if hasValue {
+			values = append([]string{value}, values...)
+		}
+		if len(values) == 0 {
+			return args, f.missingError(fmt.Errorf("Flag %s needs an argument", f.Name()))
+		}
+		f.WasSpecified = true
+		f.source = SourceCLI
+		return args[len(args):], f.setGreedyValue(values)
+	}
+	if isCluster(param) {
 		// Short flag cluster
-		args[0] = "-" + param[2:]
+		// Don't modify the caller's arguments
+		args = append([]string{"-" + param[1+len(shortName(param)):]}, args[1:]...)
+	} else if hasValue {
+		args = args[1:]
 	} else if f.NeedsExtraValue() {
+		if f.flagSet != nil && f.flagSet.root().StrictValues {
+			if _, ok := f.flagSet.FlagFor(args[1]); ok {
+				return args, fmt.Errorf("Flag %s needs an argument (got flag %s)", f.Name(), args[1])
+			}
+		}
+		if !f.acceptsValue(args[1]) {
+			return args, f.missingError(fmt.Errorf("Flag %s needs an argument", f.Name()))
+		}
+		value = args[1]
+		args = args[2:]
+	} else if f.takesBoolValue(param, args) {
 		value = args[1]
 		args = args[2:]
 	} else {
+		if f.isNegation(param) {
+			value = "false"
+		}
 		args = args[1:]
 	}
+	if f.accumulates() && f.WasSpecified {
+		return args, f.reduceValue(value)
+	}
 	f.WasSpecified = true
+	f.source = SourceCLI
 	return args, f.setValue(value)
 }
+
+// reduceValue combines value with the current value of an accumulating
+// flag.
+func (f *Flag) reduceValue(value string) error {
+	current := f.value.Int()
+	err := f.setValue(value)
+	if err != nil {
+		return err
+	}
+	f.value.SetInt(f.optionMeta["reduce"].(reducer)(current, f.value.Int()))
+	return nil
+}
+
+// takesBoolValue returns true if the boolean flag referenced by param
+// consumes the following "true" or "false" because of BoolTakesValue.
+func (f *Flag) takesBoolValue(param string, args []string) bool {
+	if f.value.Kind() != reflect.Bool || f.isNegation(param) || len(args) < 2 ||
+		f.flagSet == nil || !f.flagSet.root().BoolTakesValue {
+		return false
+	}
+	return args[1] == "true" || args[1] == "false"
+}
+
+// acceptsValue returns true if arg can be used as the flag's separate value.
+// If the FlagSet has RejectDashValues set, arguments starting with a dash
+// are only accepted if the flag has the `allow-dash-value` option or arg is
+// a negative number given to a numeric flag. A single dash is always
+// accepted, "--" never.
+func (f *Flag) acceptsValue(arg string) bool {
+	if arg == "--" {
+		return false
+	}
+	if !strings.HasPrefix(arg, "-") || arg == "-" {
+		return true
+	}
+	if f.flagSet == nil || !f.flagSet.root().RejectDashValues {
+		return true
+	}
+	allow, _ := f.optionMeta["allow_dash_value"].(bool)
+	return allow || f.isNegativeNumber(arg)
+}
+
+// isNegativeNumber returns true if the flag is numeric and arg is a
+// negative number which is not the name of a known flag.
+func (f *Flag) isNegativeNumber(arg string) bool {
+	t := f.value.Type()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+	default:
+		return false
+	}
+	if _, err := strconv.ParseFloat(arg, 64); err != nil {
+		return false
+	}
+	if f.flagSet != nil {
+		if _, ok := f.flagSet.FlagFor(arg); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// setGreedyValue assigns all values to a greedy flag. A string flag gets
+// the values joined by a space.
+func (f *Flag) setGreedyValue(values []string) error {
+	if f.value.Kind() == reflect.String {
+		return f.setValue(strings.Join(values, " "))
+	}
+	for _, value := range values {
+		err := f.setValue(value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
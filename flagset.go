@@ -1,10 +1,15 @@
 package goptions
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -15,17 +20,124 @@ type FlagSet struct {
 	// This HelpFunc will be called when PrintHelp() is called.
 	HelpFunc
 	// Name of the program. Might be used by HelpFunc.
-	Name          string
-	helpFlag      *Flag
+	Name string
+	// Alternative names of a verb. Might be used by HelpFunc.
+	Aliases []string
+	// Description of the program and notes to be shown after the options.
+	// Might be used by HelpFunc.
+	Description   string
+	Epilog        string
 	remainderFlag *Flag
+	greedyFlag    *Flag
+	positionals   []*Flag
+	structValue   reflect.Value
+	index         int
 	shortMap      map[string]*Flag
 	longMap       map[string]*Flag
 	verbFlag      *Flag
+	selectedVerb  *FlagSet
+	chainedVerbs  []*FlagSet
+	args          []string
+	passedThrough []string
+	closers       []io.Closer
+	argOffset     int
+	diagnosing    bool
+	warnings      []Diagnostic
 	// Global option flags
 	Flags []*Flag
 	// Verbs and corresponding FlagSets
-	Verbs  map[string]*FlagSet
-	parent *FlagSet
+	Verbs map[string]*FlagSet
+	// If ValidateOnly is set, Parse() performs all checks but skips
+	// side effects like opening files. The skipped actions are recorded
+	// in SkippedActions.
+	ValidateOnly   bool
+	SkippedActions []string
+	// If PassThroughUnknown is set, unknown flags are put into the
+	// Remainder instead of ending the parsing of flags. If an unknown flag
+	// does not use the equals notation and is followed by an argument not
+	// starting with a dash, that argument is considered to be its value
+	// and is put into the Remainder as well. The passed tokens are
+	// returned by PassedThrough().
+	PassThroughUnknown bool
+	// If CollectErrors is set, Parse() does not stop at the first error
+	// but returns all errors as Errors.
+	CollectErrors bool
+	// If StrictValues is set, a flag never uses another known flag as
+	// its separate value, not even with the `allow-dash-value` option.
+	StrictValues bool
+	// If RejectDashValues is set, a separate value starting with a dash
+	// (except for "-" itself) is only accepted if the flag has the
+	// `allow-dash-value` option or if it is a negative number given to a
+	// numeric flag.
+	RejectDashValues bool
+	// If LastWins is set, all flags behave as if they had the `override`
+	// option.
+	LastWins bool
+	// If EnvPrefix is set, flags without the `env` option fall back to
+	// the environment variable named after the prefix and the long name
+	// of the flag, e.g. APP_DRY_RUN for --dry-run with the prefix "APP".
+	EnvPrefix string
+	// If UnknownVerbHandler is set, it is called instead of failing when
+	// the argument at the position of the verb is not the name of a verb,
+	// e.g. to dispatch to an external plugin. Parse() returns its error
+	// and performs no further checks.
+	UnknownVerbHandler func(name string, args []string) error
+	// If Interactive is set, the values of missing obligatory flags with
+	// the `prompt` option are read from PromptInput, which defaults to
+	// stdin if it is a terminal. The prompts are written to PromptOutput,
+	// which defaults to stderr. Input for secret flags is not echoed on
+	// Unix systems with stty, elsewhere it is echoed.
+	Interactive   bool
+	PromptInput   io.Reader
+	PromptOutput  io.Writer
+	prompter      *bufio.Reader
+	prompterInput io.Reader
+	// If DecimalComma is set, floating point values use a comma as the
+	// decimal separator and may use dots as thousands separators
+	// (e.g. `1.234,5`).
+	DecimalComma bool
+	// If FullProgramName is set, the help shows the Name of the program
+	// with its directories. For the package-level Parse() and
+	// ParseAndFail(), it is set by the package-level FullProgramName.
+	FullProgramName bool
+	// If ErrorFormatter is set, it renders the errors printed by
+	// ParseAndFail(), e.g. to localize or decorate them. It defaults to
+	// the Error() method of the error.
+	ErrorFormatter func(err error) string
+	// If RequireVerb is set, Parse() fails with an ErrVerbRequired if a
+	// FlagSet has verbs but none of them has been given.
+	RequireVerb bool
+	// If AllowVerbChaining is set, a comma-separated list of verbs (e.g.
+	// `build,test,deploy`) selects all of them in the given order. The
+	// chained verbs do not take any flags, the following arguments are put
+	// into the Remainder. See SelectedVerbs().
+	AllowVerbChaining bool
+	// HelpWidth is the width of the help in columns. If it is 0, the
+	// width is taken from the COLUMNS environment variable. See
+	// DefaultHelpFunc.
+	HelpWidth int
+	// If ArgsFromStdinWhenEmpty is set and Parse() is called without
+	// arguments, the arguments are read from ArgsInput and split like
+	// SplitArgs() does (e.g. `echo "--force --name x" | tool`). ArgsInput
+	// defaults to stdin if it is not a terminal.
+	ArgsFromStdinWhenEmpty bool
+	ArgsInput              io.Reader
+	// ObligatoryMarker is appended to obligatory flags in the help. It
+	// defaults to "(*)".
+	ObligatoryMarker string
+	// If SortFlags is set, the help lists the flags sorted by their long
+	// names (or short names if they have none) instead of in the order of
+	// their declaration. See HelpFlags().
+	SortFlags bool
+	// If PrintUsageOnError is set, ParseAndFail() prints the synopsis
+	// after the error instead of the full help. See PrintError().
+	PrintUsageOnError bool
+	// If BoolTakesValue is set, a boolean flag given without the equals
+	// notation consumes the following argument if it is "true" or "false"
+	// (e.g. `--force true`). This makes such arguments ambiguous with
+	// positional arguments, so the equals notation is preferable.
+	BoolTakesValue bool
+	parent         *FlagSet
 }
 
 // NewFlagSet returns a new FlagSet containing all the flags which result from
@@ -42,18 +154,31 @@ func NewFlagSet(name string, v interface{}) *FlagSet {
 	if structValue.Kind() != reflect.Struct {
 		panic("Value type is not a pointer to a struct")
 	}
-	return newFlagset(name, structValue, nil)
+	return newFlagset(name, structValue, nil, false)
+}
+
+// NewLenientFlagSet works like NewFlagSet but ignores unknown tag options
+// instead of panicking, e.g. to share tags with other libraries. Errors in
+// known options still cause a panic.
+func NewLenientFlagSet(name string, v interface{}) *FlagSet {
+	structValue := reflect.ValueOf(v)
+	if structValue.Kind() != reflect.Ptr || structValue.Elem().Kind() != reflect.Struct {
+		panic("Value type is not a pointer to a struct")
+	}
+	return newFlagset(name, structValue.Elem(), nil, true)
 }
 
 // Internal version which skips type checking and takes the "parent"'s
-// remainder flag as a parameter.
-func newFlagset(name string, structValue reflect.Value, parent *FlagSet) *FlagSet {
+// remainder flag as a parameter. If lenient is set, unknown tag options
+// are ignored.
+func newFlagset(name string, structValue reflect.Value, parent *FlagSet, lenient bool) *FlagSet {
 	var once sync.Once
 	r := &FlagSet{
-		Name:     name,
-		Flags:    make([]*Flag, 0),
-		HelpFunc: DefaultHelpFunc,
-		parent:   parent,
+		Name:        name,
+		Flags:       make([]*Flag, 0),
+		HelpFunc:    DefaultHelpFunc,
+		parent:      parent,
+		structValue: structValue,
 	}
 
 	if parent != nil && parent.remainderFlag != nil {
@@ -65,22 +190,43 @@ func newFlagset(name string, structValue reflect.Value, parent *FlagSet) *FlagSe
 	for i = 0; i < structValue.Type().NumField(); i++ {
 		fieldValue := structValue.Field(i)
 		tag := structValue.Type().Field(i).Tag.Get("goptions")
-		flag, err := parseStructField(fieldValue, tag)
+		flag, err := parseTag(fieldValue, tag, lenient)
 
 		if err != nil {
 			panic(fmt.Sprintf("Invalid struct field: %s", err))
 		}
+		flag.flagSet = r
+		flag.index = i
 		if fieldValue.Type().Name() == "Verbs" {
 			r.verbFlag = flag
 			break
 		}
-		if fieldValue.Type().Name() == "Help" {
-			r.helpFlag = flag
-		}
-		if fieldValue.Type().Name() == "Remainder" && r.remainderFlag == nil {
+		if (fieldValue.Type().Name() == "Remainder" ||
+			fieldValue.Type().Name() == "RemainderString") && r.remainderFlag == nil {
 			r.remainderFlag = flag
 		}
 
+		if greedy, _ := flag.optionMeta["greedy"].(bool); greedy {
+			if r.greedyFlag != nil {
+				panic(fmt.Sprintf("Multiple greedy flags: %s, %s", r.greedyFlag.Name(), flag.Name()))
+			}
+			r.greedyFlag = flag
+		}
+
+		if flag.isPositional() {
+			if len(flag.Short) > 0 || len(flag.Long) > 0 {
+				panic(fmt.Sprintf("Positional argument %s must not have flag names", flag.Name()))
+			}
+			if n := len(r.positionals); n > 0 && r.positionals[n-1].IsMulti() {
+				panic(fmt.Sprintf("Positional argument %s follows variadic %s", structValue.Type().Field(i).Name, r.positionals[n-1].Name()))
+			}
+			if _, ok := flag.optionMeta["metavar"]; !ok {
+				flag.optionMeta["metavar"] = strings.ToUpper(structValue.Type().Field(i).Name)
+			}
+			r.positionals = append(r.positionals, flag)
+			continue
+		}
+
 		if len(tag) != 0 {
 			r.Flags = append(r.Flags, flag)
 		}
@@ -93,82 +239,473 @@ func newFlagset(name string, structValue reflect.Value, parent *FlagSet) *FlagSe
 		})
 		fieldValue := structValue.Field(i)
 		tag := structValue.Type().Field(i).Tag.Get("goptions")
-		r.Verbs[tag] = newFlagset(tag, fieldValue, r)
+		names := strings.Split(tag, ",")
+		seen := make(map[string]bool, len(names))
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+			if _, ok := r.verbByName(names[i]); ok || seen[names[i]] {
+				panic(fmt.Sprintf("Invalid struct field: Verb %s already exists", names[i]))
+			}
+			seen[names[i]] = true
+		}
+		verb := newFlagset(names[0], fieldValue, r, lenient)
+		verb.Aliases = names[1:]
+		verb.index = i
+		r.Verbs[names[0]] = verb
 	}
 	r.createMaps()
+	if parent == nil {
+		r.checkConditions()
+	}
 	return r
 }
 
+// checkConditions panics if an `obligatory-if` condition of the FlagSet or
+// of its verbs references an unknown flag or an `obligatory-for` option
+// references an unknown verb, even for a lenient FlagSet.
+func (fs *FlagSet) checkConditions() {
+	for _, f := range fs.allFlags() {
+		conditions, _ := f.optionMeta["obligatory_if"].([]condition)
+		for _, c := range conditions {
+			if fs.lookupInterpolated(c.name) == nil {
+				panic(fmt.Sprintf("Invalid struct field: Unknown flag %s in condition of %s", c.name, f.Name()))
+			}
+		}
+		verbs, _ := f.optionMeta["obligatory_for"].([]string)
+		for _, name := range verbs {
+			if _, ok := fs.Verbs[name]; !ok {
+				panic(fmt.Sprintf("Invalid struct field: Unknown verb %s in obligatory-for of %s", name, f.Name()))
+			}
+		}
+	}
+	for _, verb := range fs.Verbs {
+		verb.checkConditions()
+	}
+}
+
 var (
-	ErrHelpRequest = errors.New("Request for Help")
+	ErrHelpRequest    = errors.New("Request for Help")
+	ErrHelpAllRequest = errors.New("Request for full Help")
+	ErrVersionRequest = errors.New("Request for Version")
+
+	ErrCompletionRequest = errors.New("Request for Completion")
 )
 
+// markerErrors maps the types of the flags which short-circuit Parse() to
+// the errors returned when they are specified. No checks of the other flags
+// are performed in that case.
+var markerErrors = map[reflect.Type]error{
+	reflect.TypeOf(new(Help)).Elem():    ErrHelpRequest,
+	reflect.TypeOf(new(HelpAll)).Elem(): ErrHelpAllRequest,
+	reflect.TypeOf(new(Version)).Elem(): ErrVersionRequest,
+
+	reflect.TypeOf(new(Completion)).Elem(): ErrCompletionRequest,
+}
+
+// ErrVerbRequired is returned by Parse() if RequireVerb is set and no verb
+// has been given. Its message lists the verbs of the FlagSet with their
+// descriptions.
+type ErrVerbRequired struct {
+	FlagSet *FlagSet
+}
+
+func (e *ErrVerbRequired) Error() string {
+	var b bytes.Buffer
+	b.WriteString("A verb must be specified, one of:\n")
+	e.FlagSet.writeVerbList(&b)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// isMarkerRequest returns true if err has been returned because a flag
+// short-circuiting Parse() has been specified.
+func isMarkerRequest(err error) bool {
+	for _, e := range markerErrors {
+		if err == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors is returned by Parse() if CollectErrors is set and more than one
+// error occurred.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
 // Parse takes the command line arguments and sets the corresponding values
 // in the FlagSet's struct.
 func (fs *FlagSet) Parse(args []string) (err error) {
+	if len(args) == 0 && fs.parent == nil && fs.ArgsFromStdinWhenEmpty {
+		args, err = fs.argsFromStdin()
+		if err != nil {
+			return
+		}
+	}
+	collect := fs.root().CollectErrors
+	errs := make([]error, 0)
+	start := len(args)
 	// Parse global flags
+	terminated := false
+	passed := make([]string, 0)
 	for len(args) > 0 {
-		if !((isLong(args[0]) && fs.hasLongFlag(args[0][2:])) ||
-			(isShort(args[0]) && fs.hasShortFlag(args[0][1:2]))) {
+		if args[0] == "--" {
+			args = args[1:]
+			terminated = true
+			break
+		}
+		if !((isLong(args[0]) && fs.hasLongFlag(longName(args[0]))) ||
+			(isShort(args[0]) && fs.hasShortFlag(shortName(args[0])))) {
+			if (isLong(args[0]) || isShort(args[0])) && fs.root().PassThroughUnknown {
+				n := 1
+				if len(args) > 1 && !strings.Contains(args[0], "=") && !strings.HasPrefix(args[1], "-") {
+					n = 2
+				}
+				passed = append(passed, args[:n]...)
+				args = args[n:]
+				continue
+			}
 			break
 		}
+		if isShort(args[0]) {
+			err = fs.checkCluster(args[0])
+			if err != nil {
+				if !collect {
+					return
+				}
+				errs = append(errs, err)
+				args = args[1:]
+				continue
+			}
+		}
 		f := fs.FlagByName(args[0])
-		args, err = f.Parse(args)
+		var rest []string
+		rest, err = f.Parse(args)
+		if isMarkerRequest(err) {
+			return err
+		}
+		if message := f.Deprecated(); err == nil && message != "" {
+			fs.warn(fs.argOffset+start-len(args), fmt.Sprintf("%s is deprecated: %s", f.Name(), message))
+		}
 		if err != nil {
+			if !collect {
+				return
+			}
+			errs = append(errs, err)
+			if len(rest) == len(args) {
+				rest = args[1:]
+			}
+		}
+		args = rest
+	}
+
+	err = fs.applyEnv()
+	if err != nil {
+		if !collect {
 			return
 		}
-		if f == fs.helpFlag && f.WasSpecified {
-			return ErrHelpRequest
+		errs = append(errs, err)
+	}
+
+	err = fs.applyInterpolatedDefaults()
+	if err != nil {
+		if !collect {
+			return
 		}
+		errs = append(errs, err)
 	}
 
+	fs.passedThrough = passed
+
 	// Process verb
-	if len(args) > 0 {
-		if verb, ok := fs.Verbs[args[0]]; ok {
-			fs.verbFlag.value.Set(reflect.ValueOf(Verbs(args[0])))
+	if len(args) > 0 && !terminated {
+		if verb, ok := fs.verbByName(args[0]); ok {
+			if fs.verbFlag != nil {
+				fs.verbFlag.value.Set(reflect.ValueOf(Verbs(verb.Name)))
+			}
+			fs.selectedVerb = verb
+			verb.args = append([]string{}, args[1:]...)
+			verb.argOffset = fs.argOffset + start - len(args) + 1
 			err := verb.Parse(args[1:])
-			if err != nil {
+			if isMarkerRequest(err) || (err != nil && !collect) {
 				return err
+			} else if verbErrs, ok := err.(Errors); ok {
+				errs = append(errs, verbErrs...)
+			} else if err != nil {
+				errs = append(errs, err)
 			}
 			args = args[0:0]
+		} else if verbs, ok := fs.verbChain(args[0]); ok {
+			names := make([]string, 0, len(verbs))
+			for _, verb := range verbs {
+				names = append(names, verb.Name)
+			}
+			if fs.verbFlag != nil {
+				fs.verbFlag.value.Set(reflect.ValueOf(Verbs(strings.Join(names, ","))))
+			}
+			fs.chainedVerbs = verbs
+			fs.selectedVerb = verbs[len(verbs)-1]
+			for _, verb := range verbs {
+				verb.args = []string{}
+				err := verb.Parse([]string{})
+				if isMarkerRequest(err) || (err != nil && !collect) {
+					return err
+				} else if verbErrs, ok := err.(Errors); ok {
+					errs = append(errs, verbErrs...)
+				} else if err != nil {
+					errs = append(errs, err)
+				}
+			}
+			args = args[1:]
+		} else if fs.UnknownVerbHandler != nil && len(fs.Verbs) > 0 && !strings.HasPrefix(args[0], "-") {
+			return fs.UnknownVerbHandler(args[0], args[1:])
+		}
+	}
+
+	// Process positional arguments
+	if fs.selectedVerb == nil {
+		for _, f := range fs.positionals {
+			if len(args) == 0 {
+				break
+			}
+			n := 1
+			if f.IsMulti() {
+				n = len(args)
+			}
+			for _, arg := range args[:n] {
+				err = f.setValue(arg)
+				if err != nil {
+					break
+				}
+			}
+			if err != nil {
+				if !collect {
+					return
+				}
+				errs = append(errs, err)
+			}
+			f.WasSpecified = true
+			f.source = SourceCLI
+			args = args[n:]
 		}
 	}
 
 	// Process remainder
+	args = append(passed, args...)
 	if len(args) > 0 {
 		if fs.remainderFlag == nil {
-			return fmt.Errorf("Invalid trailing arguments: %v", args)
+			position := -1
+			if len(passed) == 0 {
+				position = fs.argOffset + start - len(args)
+			}
+			err = &ErrTrailingArguments{fs, args, position}
+			if !collect {
+				return
+			}
+			errs = append(errs, err)
+		} else if fs.remainderFlag.value.Kind() == reflect.String {
+			remainder := reflect.ValueOf(strings.Join(args, " "))
+			fs.remainderFlag.value.Set(remainder.Convert(fs.remainderFlag.value.Type()))
+		} else {
+			remainder := reflect.MakeSlice(fs.remainderFlag.value.Type(), len(args), len(args))
+			reflect.Copy(remainder, reflect.ValueOf(args))
+			fs.remainderFlag.value.Set(remainder)
 		}
-		remainder := reflect.MakeSlice(fs.remainderFlag.value.Type(), len(args), len(args))
-		reflect.Copy(remainder, reflect.ValueOf(args))
-		fs.remainderFlag.value.Set(remainder)
+	}
+
+	err = fs.promptMissing()
+	if err != nil {
+		if !collect {
+			return
+		}
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, fs.checkConstraints()...)
+	if len(errs) == 0 {
+		return fs.validate()
+	} else if len(errs) == 1 || !collect {
+		return errs[0]
+	}
+	return Errors(errs)
+}
+
+// checkConstraints validates the flags after parsing.
+func (fs *FlagSet) checkConstraints() []error {
+	errs := make([]error, 0)
+	// Check for a missing verb
+	if len(fs.Verbs) > 0 && fs.selectedVerb == nil && fs.root().RequireVerb {
+		errs = append(errs, &ErrVerbRequired{FlagSet: fs})
 	}
 
 	// Check for unset, obligatory, single Flags
+	flags := fs.allFlags()
+	missing := make([]*Flag, 0)
+	names := make([]string, 0)
+	for _, f := range flags {
+		if f.isObligatory(fs.selectedVerbs()...) && !f.isSet() {
+			missing = append(missing, f)
+			names = append(names, f.Name())
+		}
+	}
+	if len(missing) == 1 {
+		errs = append(errs, missing[0].missingError(fmt.Errorf("%s must be specified", missing[0].Name())))
+	} else if len(missing) > 1 {
+		errs = append(errs, fmt.Errorf("Missing required flags: %s", strings.Join(names, ", ")))
+	}
+
+	// Check for unset flags whose obligatory-if condition holds
+	for _, f := range flags {
+		err := f.checkObligatoryIf()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	for _, f := range fs.Flags {
-		if f.Obligatory && !f.WasSpecified && len(f.MutexGroups) == 0 {
-			return fmt.Errorf("%s must be specified", f.Name())
+		if min, ok := f.optionMeta["min"].(int); ok && f.value.Len() < min {
+			errs = append(errs, fmt.Errorf("flag %s requires at least %d values (got %d)", f.Name(), min, f.value.Len()))
 		}
 	}
 
 	// Check for multiple set Flags in one mutex group
 	// Check also for unset, obligatory mutex groups
 	mgs := fs.MutexGroups()
-	for _, mg := range mgs {
+	for name, mg := range mgs {
 		if !mg.IsValid() {
-			return fmt.Errorf("Exactly one of %s must be specified", strings.Join(mg.Names(), ", "))
+			errs = append(errs, &ErrMutexGroupViolation{
+				Name:          name,
+				Group:         mg,
+				NoneSpecified: !mg.WasSpecified(),
+			})
+		}
+	}
+
+	// Check for partially set all-or-none groups
+	ags := fs.allGroups()
+	for _, name := range sortedGroupNames(ags) {
+		set, missing := make([]string, 0), make([]string, 0)
+		for _, f := range ags[name] {
+			if f.isSet() {
+				set = append(set, f.Name())
+			} else {
+				missing = append(missing, f.Name())
+			}
+		}
+		if len(set) == 1 && len(missing) > 0 {
+			errs = append(errs, fmt.Errorf("%s requires %s", set[0], joinNames(missing)))
+		} else if len(set) > 0 && len(missing) > 0 {
+			errs = append(errs, fmt.Errorf("%s require %s", joinNames(set), joinNames(missing)))
+		}
+	}
+	return errs
+}
+
+// verbByName returns the verb with the given name or alias.
+func (fs *FlagSet) verbByName(name string) (*FlagSet, bool) {
+	if verb, ok := fs.Verbs[name]; ok {
+		return verb, true
+	}
+	for _, verb := range fs.Verbs {
+		for _, alias := range verb.Aliases {
+			if alias == name {
+				return verb, true
+			}
 		}
 	}
+	return nil, false
+}
+
+// verbChain returns the verbs of a comma-separated list of verbs if
+// AllowVerbChaining is set and all elements of the list are verbs.
+func (fs *FlagSet) verbChain(arg string) ([]*FlagSet, bool) {
+	if !fs.root().AllowVerbChaining || !strings.Contains(arg, ",") {
+		return nil, false
+	}
+	names := strings.Split(arg, ",")
+	r := make([]*FlagSet, 0, len(names))
+	for _, name := range names {
+		verb, ok := fs.verbByName(name)
+		if !ok {
+			return nil, false
+		}
+		r = append(r, verb)
+	}
+	return r, true
+}
+
+// selectedVerbs returns the verbs selected by the last call to Parse().
+func (fs *FlagSet) selectedVerbs() []*FlagSet {
+	if fs.chainedVerbs != nil {
+		return fs.chainedVerbs
+	}
+	if fs.selectedVerb != nil {
+		return []*FlagSet{fs.selectedVerb}
+	}
 	return nil
 }
 
+// allFlags returns the flags and the positional arguments of the FlagSet in
+// a new slice, which does not share its backing array with fs.Flags.
+func (fs *FlagSet) allFlags() []*Flag {
+	return append(append(make([]*Flag, 0, len(fs.Flags)+len(fs.positionals)), fs.Flags...), fs.positionals...)
+}
+
+// root returns the FlagSet of the program, i.e. the top-most parent.
+func (fs *FlagSet) root() *FlagSet {
+	for fs.parent != nil {
+		fs = fs.parent
+	}
+	return fs
+}
+
+func (fs *FlagSet) isValidateOnly() bool {
+	return fs.root().ValidateOnly
+}
+
+func (fs *FlagSet) skipAction(format string, a ...interface{}) {
+	r := fs.root()
+	r.SkippedActions = append(r.SkippedActions, fmt.Sprintf(format, a...))
+}
+
 func (fs *FlagSet) createMaps() {
 	fs.longMap = make(map[string]*Flag)
 	fs.shortMap = make(map[string]*Flag)
 	for _, flag := range fs.Flags {
-		fs.longMap[flag.Long] = flag
-		fs.shortMap[flag.Short] = flag
+		if len(flag.Long) > 0 {
+			fs.longMap[flag.Long] = flag
+		}
+		if len(flag.Short) > 0 {
+			fs.shortMap[flag.Short] = flag
+		}
+		for _, name := range flag.NegatedLongs() {
+			fs.longMap[name] = flag
+		}
+		for _, name := range flag.aliases {
+			fs.longMap[name] = flag
+		}
+	}
+}
+
+// checkCluster makes sure that every flag in a cluster of short flags
+// is known.
+func (fs *FlagSet) checkCluster(arg string) error {
+	for _, r := range arg[1:] {
+		if r == '=' {
+			// The rest is the value of the last flag
+			break
+		}
+		if !fs.hasShortFlag(string(r)) {
+			return fmt.Errorf("Unknown flag -%c in cluster %s", r, arg)
+		}
 	}
+	return nil
 }
 
 func (fs *FlagSet) hasLongFlag(fname string) bool {
@@ -182,14 +719,74 @@ func (fs *FlagSet) hasShortFlag(fname string) bool {
 }
 
 func (fs *FlagSet) FlagByName(fname string) *Flag {
-	if isShort(fname) && fs.hasShortFlag(fname[1:2]) {
-		return fs.shortMap[fname[1:2]]
-	} else if isLong(fname) && fs.hasLongFlag(fname[2:]) {
-		return fs.longMap[fname[2:]]
+	if isShort(fname) && fs.hasShortFlag(shortName(fname)) {
+		return fs.shortMap[shortName(fname)]
+	} else if isLong(fname) && fs.hasLongFlag(longName(fname)) {
+		return fs.longMap[longName(fname)]
 	}
 	return nil
 }
 
+// FlagFor returns the flag which would handle arg.
+func (fs *FlagSet) FlagFor(arg string) (*Flag, bool) {
+	for _, f := range fs.Flags {
+		if f.Handles(arg) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// allGroups returns a map of Flag lists which have to be specified
+// either all together or not at all.
+func (fs *FlagSet) allGroups() map[string][]*Flag {
+	r := make(map[string][]*Flag)
+	for _, f := range fs.Flags {
+		groups, _ := f.optionMeta["group_all"].([]string)
+		for _, group := range groups {
+			if len(group) == 0 {
+				continue
+			}
+			r[group] = append(r[group], f)
+		}
+	}
+	return r
+}
+
+// joinNames joins names like "--a, --b and --c".
+func joinNames(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+func sortedGroupNames(groups map[string][]*Flag) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RequiredFlags returns the flags and positional arguments which have to be
+// specified, i.e. the obligatory ones which are not part of a mutex group
+// and the ones which are obligatory for the selected verb. After Parse(),
+// the required flags of the selected verbs are appended.
+func (fs *FlagSet) RequiredFlags() []*Flag {
+	r := make([]*Flag, 0)
+	for _, f := range fs.allFlags() {
+		if f.isObligatory(fs.selectedVerbs()...) {
+			r = append(r, f)
+		}
+	}
+	for _, verb := range fs.selectedVerbs() {
+		r = append(r, verb.RequiredFlags()...)
+	}
+	return r
+}
+
 // MutexGroups returns a map of Flag lists which contain mutually
 // exclusive flags.
 func (fs *FlagSet) MutexGroups() map[string]MutexGroup {
@@ -208,7 +805,302 @@ func (fs *FlagSet) MutexGroups() map[string]MutexGroup {
 	return r
 }
 
+// Constraints returns human-readable descriptions of the constraints between
+// the flags of the FlagSet and its verbs, i.e. the MutexGroups and the flags
+// required by verbs. Constraints of verbs are prefixed with the verb's name.
+func (fs *FlagSet) Constraints() []string {
+	r := make([]string, 0)
+	mgs := fs.MutexGroups()
+	names := make([]string, 0, len(mgs))
+	for name := range mgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mg := mgs[name]
+		if mg.IsObligatory() {
+			r = append(r, fmt.Sprintf("Exactly one of %s", strings.Join(mg.Names(), ", ")))
+		} else {
+			r = append(r, fmt.Sprintf("At most one of %s", strings.Join(mg.Names(), ", ")))
+		}
+	}
+	ags := fs.allGroups()
+	for _, name := range sortedGroupNames(ags) {
+		flagNames := make([]string, 0, len(ags[name]))
+		for _, f := range ags[name] {
+			flagNames = append(flagNames, f.Name())
+		}
+		r = append(r, fmt.Sprintf("All or none of %s", strings.Join(flagNames, ", ")))
+	}
+	for _, f := range fs.Flags {
+		if verbs, ok := f.optionMeta["obligatory_for"].([]string); ok {
+			r = append(r, fmt.Sprintf("%s is required by %s", f.Name(), strings.Join(verbs, ", ")))
+		}
+	}
+	names = make([]string, 0, len(fs.Verbs))
+	for name := range fs.Verbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, c := range fs.Verbs[name].Constraints() {
+			r = append(r, name+": "+c)
+		}
+	}
+	return r
+}
+
+// Dump writes the current values of all flags to the given writer, one
+// flag per line. Flags of verbs are prefixed with the verb's name. Values of
+// secret flags are redacted.
+func (fs *FlagSet) Dump(w io.Writer) {
+	fs.dump(w, "")
+}
+
+func (fs *FlagSet) dump(w io.Writer, prefix string) {
+	for _, f := range fs.Flags {
+		if f.isMarker() {
+			continue
+		}
+		fmt.Fprintf(w, "%s%s=%s\n", prefix, f.Name(), f)
+	}
+	names := make([]string, 0, len(fs.Verbs))
+	for name := range fs.Verbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fs.Verbs[name].dump(w, prefix+name+" ")
+	}
+}
+
+// SetDescription sets the description of the program which is shown
+// before the options by the DefaultHelpFunc.
+func (fs *FlagSet) SetDescription(description string) {
+	fs.Description = description
+}
+
+// SetEpilog sets the notes which are shown after the options by the
+// DefaultHelpFunc.
+func (fs *FlagSet) SetEpilog(epilog string) {
+	fs.Epilog = epilog
+}
+
+// ParseAndFail parses the given arguments and prints the help if an error
+// occurs, like the package-level ParseAndFail(). Requests of marker flags
+// exit with status 0: Help and HelpAll print the help, Completion writes
+// the completion script and Version exits without output, so programs
+// printing their version should handle ErrVersionRequest with Parse().
+func (fs *FlagSet) ParseAndFail(args []string) {
+	err := fs.Parse(args)
+	if err == ErrCompletionRequest {
+		fs.WriteBashCompletion(os.Stdout)
+		os.Exit(0)
+	}
+	if err == ErrVersionRequest {
+		os.Exit(0)
+	}
+	if err != nil {
+		errCode := 0
+		if !isMarkerRequest(err) {
+			errCode = 1
+			fs.PrintError(os.Stdout, err)
+			if fs.root().PrintUsageOnError {
+				os.Exit(errCode)
+			}
+		}
+		fs.PrintHelp(os.Stderr)
+		os.Exit(errCode)
+	}
+}
+
+// PrintError writes err rendered by FormatError() to the given writer. If
+// PrintUsageOnError is set, it is followed by the synopsis of the selected
+// verb.
+func (fs *FlagSet) PrintError(w io.Writer, err error) {
+	fmt.Fprintf(w, "Error: %s\n", fs.FormatError(err))
+	if !fs.root().PrintUsageOnError {
+		return
+	}
+	verb := fs
+	for verb.selectedVerb != nil {
+		verb = verb.selectedVerb
+	}
+	usage := verb.Synopsis()
+	if verb.parent != nil {
+		usage = verb.parent.FullName() + " " + usage
+	}
+	fmt.Fprintf(w, "Usage: %s\n", usage)
+}
+
+// FormatError renders err using the ErrorFormatter of the FlagSet, or
+// returns err.Error() if none is set.
+func (fs *FlagSet) FormatError(err error) string {
+	if f := fs.root().ErrorFormatter; f != nil {
+		return f(err)
+	}
+	return err.Error()
+}
+
 // Prints the FlagSet's help to the given writer.
 func (fs *FlagSet) PrintHelp(w io.Writer) {
 	fs.HelpFunc(w, fs)
 }
+
+// PrintHelpForVerb prints the help of the given verb to the given writer.
+// An error is returned if there is no such verb.
+func (fs *FlagSet) PrintHelpForVerb(w io.Writer, verb string) error {
+	v, ok := fs.verbByName(verb)
+	if !ok {
+		return fmt.Errorf("Unknown verb %s", verb)
+	}
+	v.PrintHelp(w)
+	return nil
+}
+
+// Set sets the value of the named flag as if it had been given on the
+// command line, including all checks of the value like choices. The name
+// can be given with or without leading dashes.
+func (fs *FlagSet) Set(name, value string) error {
+	f, err := fs.lookupFlag(name)
+	if err != nil {
+		return err
+	}
+	err = f.setValue(value)
+	if err != nil {
+		return err
+	}
+	f.WasSpecified = true
+	f.source = SourceCLI
+	return nil
+}
+
+// Close closes the files opened for the *os.File flags of the FlagSet and
+// its verbs. The errors of all files are returned as Errors if there is
+// more than one. It is safe to call Close if no file has been opened.
+func (fs *FlagSet) Close() error {
+	root := fs.root()
+	errs := make([]error, 0)
+	for _, c := range root.closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	root.closers = nil
+	if len(errs) == 0 {
+		return nil
+	} else if len(errs) == 1 {
+		return errs[0]
+	}
+	return Errors(errs)
+}
+
+// Args returns the arguments which followed the name of the verb on the
+// command line, as they were passed to the verb's Parse(). It returns nil
+// for FlagSets which have not been selected as a verb.
+func (fs *FlagSet) Args() []string {
+	return fs.args
+}
+
+// PassedThrough returns the unknown flags and their values which have been
+// put into the Remainder because of PassThroughUnknown, in the order and
+// form in which they have been given on the command line. The ones passed
+// to the selected verbs follow the ones of the FlagSet.
+func (fs *FlagSet) PassedThrough() []string {
+	r := make([]string, 0)
+	for ; fs != nil; fs = fs.selectedVerb {
+		r = append(r, fs.passedThrough...)
+	}
+	return r
+}
+
+// ProgramName returns the Name of the program without leading
+// directories, e.g. "tool" for "/usr/local/bin/tool", unless
+// FullProgramName is set.
+func (fs *FlagSet) ProgramName() string {
+	r := fs.root()
+	if r.FullProgramName || r.Name == "" {
+		return r.Name
+	}
+	return filepath.Base(r.Name)
+}
+
+// FullName returns the name of the program followed by the names of the
+// verbs leading to the FlagSet, e.g. "tool remote add".
+func (fs *FlagSet) FullName() string {
+	if fs.parent == nil {
+		return fs.ProgramName()
+	}
+	return fs.parent.FullName() + " " + fs.Name
+}
+
+// HelpFlags returns the Flags in the order in which they are listed in the
+// help, i.e. sorted by their names if SortFlags is set.
+func (fs *FlagSet) HelpFlags() []*Flag {
+	if !fs.root().SortFlags {
+		return fs.Flags
+	}
+	r := append([]*Flag{}, fs.Flags...)
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].sortName() < r[j].sortName()
+	})
+	return r
+}
+
+// ObligatoryMark returns the ObligatoryMarker of the program or "(*)" if it
+// is not set. It is meant to be used by HelpFuncs.
+func (fs *FlagSet) ObligatoryMark() string {
+	if marker := fs.root().ObligatoryMarker; marker != "" {
+		return marker
+	}
+	return "(*)"
+}
+
+// ObligatoryLegend returns a line explaining the ObligatoryMark if the
+// FlagSet or one of its verbs has obligatory flags, or an empty string.
+// Only the flags of the direct verbs count, as the help does not list the
+// flags of nested verbs.
+func (fs *FlagSet) ObligatoryLegend() string {
+	if !fs.hasObligatoryFlags() {
+		return ""
+	}
+	return fs.ObligatoryMark() + " marks obligatory flags"
+}
+
+func (fs *FlagSet) hasObligatoryFlags() bool {
+	flagSets := []*FlagSet{fs}
+	for _, verb := range fs.Verbs {
+		flagSets = append(flagSets, verb)
+	}
+	for _, flagSet := range flagSets {
+		for _, f := range flagSet.Flags {
+			if f.Obligatory {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SelectedVerb returns the name of the verb selected by the last call to
+// Parse(), or an empty string if no verb was selected. It is set even if
+// Parse() returned ErrHelpRequest for the verb's help flag, so a HelpFunc
+// can render the help of the verb.
+func (fs *FlagSet) SelectedVerb() string {
+	if fs.selectedVerb == nil {
+		return ""
+	}
+	return fs.selectedVerb.Name
+}
+
+// SelectedVerbs returns the names of the verbs selected by the last call
+// to Parse() in the given order. Unless AllowVerbChaining is set, it
+// contains at most one verb.
+func (fs *FlagSet) SelectedVerbs() []string {
+	verbs := fs.selectedVerbs()
+	r := make([]string, 0, len(verbs))
+	for _, verb := range verbs {
+		r = append(r, verb.Name)
+	}
+	return r
+}
@@ -11,26 +11,143 @@ by the tags in a (possibly anonymous) struct.
     	Verbosity int `goptions:"-v, --verbose"`
     }
 
-Short flags can be combined (e.g. `-nfv`). Long flags take their value after a
-separating space. The equals notation (`--long-flag=value`) is NOT supported
-right now.
+Short flags can be combined (e.g. `-nfv`). Flags take their value after a
+separating space or using the equals notation (`--long-flag=value`, `-n=value`).
+A separate value may start with a dash, only "--" is never used as a value. If
+FlagSet.RejectDashValues is set, a separate value must not start with a dash
+(except for "-" itself) unless the flag has the `allow-dash-value` option. In
+that mode, numeric flags still accept negative numbers like `-5` as separate
+values as long as they are not the names of other flags.
 
 Every member of the struct which is supposed to catch a command line value
 has to have a "goptions" tag. The contains the short and long flag names for this
-member but can additionally specify any of these options below.
+member but can additionally specify any of these options below. Unknown options
+cause NewFlagSet() to panic, while NewLenientFlagSet() ignores them.
 
     obligatory        - Flag must be specified. Otherwise an error will be returned
-                        when Parse() is called.
+                        when Parse() is called. A value set in the struct
+                        does not satisfy the requirement, but a value from
+                        the environment or from a config file does. It cannot
+                        be combined with `default`. A bool can only be
+                        obligatory if it is negatable or in a mutexgroup.
+    obligatory-for='...'
+                      - Flag must be specified if one of the given
+                        comma-separated verbs has been selected. Referencing
+                        an unknown verb is a definition error.
+    obligatory-if='...'
+                      - Flag must be specified if one of the given
+                        comma-separated conditions holds after parsing. A
+                        condition `name=value` holds if the flag with the long
+                        name `name` has the value (e.g.
+                        `obligatory-if='tls-mode=verify'`). Referencing an
+                        unknown flag is a definition error.
+    env='...'         - Read the value from the given environment variable if
+                        the flag has not been specified on the command line.
+                        Overrides the name derived from FlagSet.EnvPrefix.
+    positional        - The member is not a flag but takes the next argument
+                        after the flags. Positional arguments must not have
+                        flag names. A slice takes all remaining arguments and
+                        has to be the last positional argument.
+    metavar='...'     - Set the placeholder of the value in the synopsis.
+    synopsis='...'    - Use the given text verbatim as the flag's part of the
+                        synopsis.
     description='...' - Set the description for this particular flag. Will be
                         used by the HelpFunc.
+    example='...'     - Show an example usage of the flag after its description
+                        in the help (e.g. `example='--date 2023-01-31'`).
+    deprecated='...'  - Mark the flag as deprecated with the given notice.
+                        Using it is reported as a warning by
+                        ParseDiagnostics().
+    error='...'       - Set the error message returned if the flag is obligatory
+                        but has not been specified or if its value is missing.
+    greedy            - All arguments following the flag are used as its
+                        values, even if they look like flags. Only strings
+                        (getting the arguments joined by spaces) and slices
+                        can be greedy. Only one flag of a FlagSet can be greedy.
+    transform='...'   - Comma-separated list of transforms which are applied to
+                        the value before it is parsed. Available transforms are
+                        trim, lower, upper, abspath (resolving a path relative
+                        to the working directory) and the ones added by
+                        RegisterTransform().
+    validate='...'    - Comma-separated list of validators registered with
+                        RegisterValidator() which are run on each value set
+                        for the flag.
+    allow-dash-value  - The separate value of the flag may start with a dash
+                        (e.g. `--name -x`) even if FlagSet.RejectDashValues
+                        is set. "--" is never used as a value.
+    override          - The flag can be specified multiple times and the last
+                        value wins. Without this option, specifying a flag
+                        which is not a slice more than once is an error.
+    accumulate        - The flag can be specified multiple times and the values
+                        are added up. Only int flags can accumulate, use a
+                        slice for other repeatable flags.
+    reduce='...'      - The flag can be specified multiple times and the values
+                        are combined by the given reducer: sum (like
+                        accumulate), min, max or last. Only int flags can be
+                        reduced.
+    prompt='...'      - Ask for the value of the flag if it is obligatory and
+                        missing and FlagSet.Interactive is set. The text of
+                        the prompt defaults to the name of the flag.
+    secret            - The value of this flag is redacted by Flag.String(),
+                        FlagSet.Dump() and FlagSet.MarshalJSON(), and its
+                        default is redacted in the help.
     mutexgroup='...'  - Add this flag to a MutexGroup. Only one flag of the
                         ones sharing a MutexGroup can be set. Otherwise an error
                         will be returned when Parse() is called. If one flag in a
                         MutexGroup is `obligatory` one flag of the group must be
                         specified. A flag can be in multiple MutexGroups at once.
+    group-all='...'   - Add this flag to a group of flags which must either all
+                        be specified or none of them.
+    default='...'     - Set the value of the member before parsing. The value
+                        is parsed like it would be on the command line.
+                        `${name}` is replaced with the final value of the flag
+                        with the long name `name` after parsing. `$${` stands
+                        for a literal `${`.
+    choices='...'     - Comma-separated list of values the flag accepts.
+                        Any other value results in an error.
+    choices-ci        - Compare the values with the choices case-insensitively.
+                        The value is normalized to the spelling of the choice.
+    choices-func='...' - Like choices, but the values are returned by the
+                        provider registered with RegisterChoices().
+
+The groups of `mutexgroup` and `group-all` are scoped to the global flags or
+the verb declaring them, i.e. groups of the same name in different verbs are
+independent of each other.
 
 Depending on the type of the struct member, additional options might become available:
 
+    Type: bool
+        The flag is set to true if it is specified.
+    Available options:
+        negatable - The flag can be cleared by prepending "no-" to its long
+                    name (e.g. `--no-color`).
+        negate-prefix='...' - Like negatable, but the flag can also be cleared
+                    by prepending the given prefix to its long name
+                    (e.g. `--disable-color` for `negate-prefix='disable-'`).
+        off-suffix - The flag can be cleared by appending a dash to its long
+                    name (e.g. `--color-`). Another suffix can be given
+                    with `off-suffix='...'`.
+
+    Type: float64
+        The given string is parsed by strconv.ParseFloat(). NaN and infinite
+        values are rejected.
+    Available options:
+        allow-nonfinite - Accept NaN and infinite values (e.g. `+Inf`). Also
+                          available for []float64 and for *big.Float, which
+                          rejects infinite values as well.
+
+    Type: time.Duration
+        The given string is parsed by time.ParseDuration().
+    Available options:
+        unit='...' - Unit of values given without a unit (e.g. `unit='s'`
+                     interprets "30" as 30 seconds).
+
+    Type: time.Time
+        The given string is parsed by time.Parse() using the RFC 3339 layout.
+    Available options:
+        layout='...' - Pipe-separated list of layouts which are tried in the
+                     given order (e.g. `layout='2006-01-02|2006-01-02T15:04'`).
+
     Type: *os.File
         The given string is interpreted as a path to a file. If the string is "-"
         os.Stdin or os.Stdout will be used. os.Stdin will be returned, if the
@@ -40,19 +157,65 @@ Depending on the type of the struct member, additional options might become avai
         excl, sync, trunc and perm can be specified and correspond directly with
         the combination of the homonymous flags in the os package.
 
+    Type: struct
+        The given string is a comma-separated list of key=value pairs (e.g.
+        `src=/a,dst=/b`). The keys are the goptions tags of the struct's fields
+        or the lower-cased field names. Each value is parsed according to the
+        type of its field.
+
+    Type: interface
+        The concrete type is chosen by the scheme the value starts with (e.g.
+        `file:/path` or `s3://bucket`). The constructors of the schemes are
+        registered with RegisterScheme().
+
 If a member is a slice type, multiple definitions of the flags are possible. For each
-specification the underlying type will be used.
+specification the underlying type will be used. The number of definitions can
+be limited with these options:
+
+    min='...' - The flag must be given at least this many values. A non-zero
+                minimum makes the flag obligatory.
+    max='...' - The flag can be specified at most this many times.
+
+With the replace-on-set option, the first occurrence of a slice flag on the
+command line replaces the elements set by a default or a config file instead
+of appending to them.
+
+If a member is a map type, each definition of the flag adds a key=value entry
+(e.g. `--label env=prod`). Keys and values are parsed according to the types
+of the map.
+
+With `delim='...'`, each value of a slice or map flag is split at the given
+delimiter (e.g. `--path a,b` with `delim=','` or `--label 'a=1;b=2'` with
+`delim=';'`). A delimiter preceded by a backslash is kept as part of the
+element (`--path 'a\,b,c'` yields "a,b" and "c"), other backslashes are left
+untouched.
+
+A single "--" ends the list of flags. All following arguments are put into the
+Remainder (or RemainderString), even if they look like flags or verbs.
+
+Values can also be loaded from a JSON config file with FlagSet.LoadJSON().
+The command line takes precedence over environment variables, which take
+precedence over the config file. FlagSet.Source() reports where the value of a
+flag came from. A value from the environment or the config file satisfies
+`obligatory`. Slice and map flags collect the values of all sources, e.g. the
+values given on the command line are appended to the ones of the config file.
+With `replace-on-set`, the values given on the command line replace the ones of
+the other sources.
+
+Flags can also be added to an existing FlagSet without a struct using
+FlagSet.AddFlag() or helpers like FlagSet.String(). The tags of these flags
+have the same format.
 
 goptions also has support for verbs. Each verb accepts its own set of flags which
-take exactly the same tag format as global options. For an usage example of verbs
-see the PrintHelp() example.
+take exactly the same tag format as global options. The tag of a verb member
+is the verb's name, optionally followed by a comma-separated list of aliases
+(e.g. `goptions:"remove, rm"`). For an usage example of verbs see the
+PrintHelp() example.
 */
 package goptions
 
 import (
-	"fmt"
 	"os"
-	"path/filepath"
 )
 
 const (
@@ -61,28 +224,27 @@ const (
 
 var (
 	globalFlagSet *FlagSet
+	// FullProgramName sets FlagSet.FullProgramName for the FlagSet of
+	// Parse() and ParseAndFail(), which is named after os.Args[0].
+	FullProgramName bool
 )
 
 // ParseAndFail is a convenience function to parse os.Args[1:] and print
 // the help if an error occurs. This should cover 90% of this library's
 // applications.
 func ParseAndFail(v interface{}) {
-	err := Parse(v)
-	if err != nil {
-		errCode := 0
-		if err != ErrHelpRequest {
-			errCode = 1
-			fmt.Printf("Error: %s\n", err)
-		}
-		PrintHelp()
-		os.Exit(errCode)
-	}
+	newGlobalFlagSet(v).ParseAndFail(os.Args[1:])
 }
 
 // Parse parses the command-line flags from os.Args[1:].
 func Parse(v interface{}) error {
-	globalFlagSet = NewFlagSet(filepath.Base(os.Args[0]), v)
-	return globalFlagSet.Parse(os.Args[1:])
+	return newGlobalFlagSet(v).Parse(os.Args[1:])
+}
+
+func newGlobalFlagSet(v interface{}) *FlagSet {
+	globalFlagSet = NewFlagSet(os.Args[0], v)
+	globalFlagSet.FullProgramName = FullProgramName
+	return globalFlagSet
 }
 
 // PrintHelp renders the default help to os.Stderr.
@@ -1,7 +1,13 @@
 package goptions
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"text/tabwriter"
 	"text/template"
@@ -28,23 +34,188 @@ func NewTemplatedHelpFunc(tpl string) HelpFunc {
 }
 
 const (
-	_DEFAULT_HELP = `Usage: {{.Name}} [global options] {{with .Verbs}}<verb> [verb options]{{end}}
+	// _VERB_NAME renders the name of a verb with its aliases. It is shared
+	// by the help templates and the verb list, so they cannot drift apart.
+	_VERB_NAME = `{{define "verbName"}}{{.Name}}{{with .Aliases}} ({{range $i, $alias := .}}{{if $i}}, {{end}}{{$alias}}{{end}}){{end}}{{end}}`
 
-Global options:{{range .Flags}}
-	{{with .Short}}-{{.}},{{end}}	{{with .Long}}--{{.}}{{end}}	{{.Description}}{{with .DefaultValue}} (default: {{.}}){{end}}{{if .Obligatory}} (*){{end}}{{end}}
+	_DEFAULT_HELP = _VERB_NAME + `Usage: {{.FullName}} [global options] {{range .Positionals}}{{.Synopsis}} {{end}}{{with .Verbs}}<verb> [verb options]{{end}}
+{{with .Description}}
+{{.}}
+{{end}}
+Global options:{{range .HelpFlags}}
+	{{with .Short}}-{{.}},{{end}}	{{with .Long}}--{{.}}{{end}}	{{.Description}}{{with .Example}} (e.g. {{.}}){{end}}{{with .Default}} (default: {{.}}){{end}}{{if .IsAccumulate}} (repeatable){{else if .IsMulti}} (can be set multiple times){{end}}{{if .Obligatory}} {{$.ObligatoryMark}}{{end}}{{end}}
 
 {{with .Verbs}}Verbs:{{range .}}
-	{{.Name}}:{{range .Flags}}
-		{{with .Short}}-{{.}},{{end}}	{{with .Long}}--{{.}}{{end}}	{{.Description}}{{with .DefaultValue}} (default: {{.}}){{end}}{{if .Obligatory}} (*){{end}}{{end}}{{end}}{{end}}
+	{{template "verbName" .}}:{{range .HelpFlags}}
+		{{with .Short}}-{{.}},{{end}}	{{with .Long}}--{{.}}{{end}}	{{.Description}}{{with .Example}} (e.g. {{.}}){{end}}{{with .Default}} (default: {{.}}){{end}}{{if .IsAccumulate}} (repeatable){{else if .IsMulti}} (can be set multiple times){{end}}{{if .Obligatory}} {{$.ObligatoryMark}}{{end}}{{end}}{{end}}{{end}}
 
-`
+{{with .ObligatoryLegend}}{{.}}
+
+{{end}}{{with .Constraints}}Constraints:{{range .}}
+	{{.}}{{end}}
+
+{{end}}{{with .Epilog}}{{.}}
+
+{{end}}`
+	_COMPACT_HELP = _VERB_NAME + `Usage: {{.FullName}} [global options] {{range .Positionals}}{{.Synopsis}} {{end}}{{with .Verbs}}<verb> [verb options]{{end}}
+{{with .Description}}
+{{.}}
+{{end}}
+Global options:{{range .HelpFlags}}
+  {{with .Short}}-{{.}}{{end}}{{if and .Short .Long}}, {{end}}{{with .Long}}--{{.}}{{end}}{{if .Obligatory}} {{$.ObligatoryMark}}{{end}}{{if or .Description .Example .Default .IsMulti}}
+{{describe 6 .}}{{end}}{{end}}
+
+{{with .Verbs}}Verbs:{{range .}}
+  {{template "verbName" .}}:{{range .HelpFlags}}
+    {{with .Short}}-{{.}}{{end}}{{if and .Short .Long}}, {{end}}{{with .Long}}--{{.}}{{end}}{{if .Obligatory}} {{$.ObligatoryMark}}{{end}}{{if or .Description .Example .Default .IsMulti}}
+{{describe 8 .}}{{end}}{{end}}{{end}}{{end}}
+
+{{with .ObligatoryLegend}}{{.}}
+
+{{end}}{{with .Constraints}}Constraints:{{range .}}
+  {{.}}{{end}}
+
+{{end}}{{with .Epilog}}{{.}}
+
+{{end}}`
+
+	_VERB_LIST = _VERB_NAME + `{{range .Verbs}}	{{template "verbName" .}}	{{.Description}}
+{{end}}`
+
+	// Terminals narrower than this many columns get the compact help.
+	_COMPACT_HELP_WIDTH = 60
+
+	// _WRAP_MARK marks the start of the flag descriptions in the help of
+	// NewTabwriterHelpFunc, so they can be wrapped after the alignment.
+	_WRAP_MARK = "\x00"
 )
 
+// CompactHelpFunc is a HelpFunc for narrow terminals which prints the
+// description of each flag on its own line below the flag's names. The
+// descriptions are wrapped at the HelpWidth of the FlagSet or, if it is not
+// set, at the width given by the COLUMNS environment variable.
+func CompactHelpFunc(w io.Writer, fs *FlagSet) {
+	width := fs.helpWidth()
+	t := template.Must(template.New("compactHelpTemplate").Funcs(template.FuncMap{
+		"describe": func(indent int, f *Flag) string {
+			description := f.Description
+			if example := f.Example(); example != "" {
+				description += " (e.g. " + example + ")"
+			}
+			if d := f.Default(); d != nil && !reflect.ValueOf(d).IsZero() {
+				description += fmt.Sprintf(" (default: %v)", d)
+			}
+			if f.IsAccumulate() {
+				description += " (repeatable)"
+			} else if f.IsMulti() {
+				description += " (can be set multiple times)"
+			}
+			return wrapText(strings.TrimSpace(description), indent, width)
+		},
+	}).Parse(_COMPACT_HELP))
+	err := t.Execute(w, fs)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// helpWidth returns the HelpWidth of the FlagSet or, if it is not set, the
+// width given by the COLUMNS environment variable. 0 is returned if the
+// width is unknown.
+func (fs *FlagSet) helpWidth() int {
+	if width := fs.root().HelpWidth; width > 0 {
+		return width
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 0
+}
+
+// wrapText indents the words of text by indent spaces and breaks the lines
+// before they exceed width columns. Words longer than a line are not
+// broken. If width is 0, text is not wrapped.
+func wrapText(text string, indent, width int) string {
+	prefix := strings.Repeat(" ", indent)
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return prefix
+	}
+	lines := make([]string, 0, 1)
+	line := prefix + words[0]
+	for _, word := range words[1:] {
+		if width > 0 && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = prefix + word
+			continue
+		}
+		line += " " + word
+	}
+	return strings.Join(append(lines, line), "\n")
+}
+
 // DefaultHelpFunc is a HelpFunc which renders the default help template and pipes
 // the output through a text/tabwriter.Writer before flushing it to the output.
+// The text/tabwriter.Writer uses a minwidth of 4, a tabwidth of 4, a padding
+// of 1 and spaces as padchar.
+//
+// If the HelpWidth of the FlagSet or, if it is not set, the COLUMNS
+// environment variable is less than 60 columns, the CompactHelpFunc is used
+// instead. Otherwise the descriptions of the flags are wrapped at that width
+// and continued in their column.
 func DefaultHelpFunc(w io.Writer, fs *FlagSet) {
+	if width := fs.helpWidth(); width > 0 && width < _COMPACT_HELP_WIDTH {
+		CompactHelpFunc(w, fs)
+		return
+	}
+	NewTabwriterHelpFunc(4, 4, 1, ' ')(w, fs)
+}
+
+var verbListTemplate = template.Must(template.New("verbList").Parse(_VERB_LIST))
+
+// writeVerbList writes the verbs of the FlagSet with their aliases and
+// descriptions, one per line.
+func (fs *FlagSet) writeVerbList(w io.Writer) {
 	tw := &tabwriter.Writer{}
 	tw.Init(w, 4, 4, 1, ' ', 0)
-	NewTemplatedHelpFunc(_DEFAULT_HELP)(tw, fs)
+	verbListTemplate.Execute(tw, fs)
 	tw.Flush()
 }
+
+// NewTabwriterHelpFunc generates a new HelpFunc which works like
+// DefaultHelpFunc but uses the given parameters for the text/tabwriter.Writer.
+func NewTabwriterHelpFunc(minwidth, tabwidth, padding int, padchar byte) HelpFunc {
+	plain := NewTemplatedHelpFunc(_DEFAULT_HELP)
+	marked := NewTemplatedHelpFunc(strings.Replace(_DEFAULT_HELP, "\t{{.Description}}", "\t"+_WRAP_MARK+"{{.Description}}", -1))
+	return func(w io.Writer, fs *FlagSet) {
+		tw := &tabwriter.Writer{}
+		width := fs.helpWidth()
+		if width == 0 {
+			tw.Init(w, minwidth, tabwidth, padding, padchar, 0)
+			plain(tw, fs)
+			tw.Flush()
+			return
+		}
+		var buf bytes.Buffer
+		tw.Init(&buf, minwidth, tabwidth, padding, padchar, 0)
+		marked(tw, fs)
+		tw.Flush()
+		io.WriteString(w, wrapDescriptions(buf.String(), width))
+	}
+}
+
+// wrapDescriptions wraps the text following the _WRAP_MARK of each line of
+// help at width columns, indenting the continuation lines to the column of
+// the mark.
+func wrapDescriptions(help string, width int) string {
+	lines := strings.Split(help, "\n")
+	for i, line := range lines {
+		idx := strings.Index(line, _WRAP_MARK)
+		if idx < 0 {
+			continue
+		}
+		wrapped := wrapText(line[idx+len(_WRAP_MARK):], idx, width)
+		lines[i] = line[:idx] + wrapped[idx:]
+	}
+	return strings.Join(lines, "\n")
+}
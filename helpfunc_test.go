@@ -0,0 +1,421 @@
+package goptions
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHelpFunc_DescriptionEpilog(t *testing.T) {
+	var options struct {
+		Name string `goptions:"-n, --name, description='Some name'"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	fs.SetDescription("Does something useful.")
+	fs.SetEpilog("See the manual for details.")
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	help := buf.String()
+	desc := strings.Index(help, "Does something useful.")
+	opts := strings.Index(help, "Global options:")
+	epilog := strings.Index(help, "See the manual for details.")
+	if !(desc >= 0 && opts > desc && epilog > opts) {
+		t.Fatalf("Unexpected help: %q", help)
+	}
+}
+
+func TestHelpFunc_Tabwriter(t *testing.T) {
+	var options struct {
+		Name  string `goptions:"-n, --name, description='Some name'"`
+		Force bool   `goptions:"--force, description='Force it'"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	fs.HelpFunc = NewTabwriterHelpFunc(8, 8, 2, '.')
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	expected := "Usage: goptions [global options] \n" +
+		"\n" +
+		"Global options:\n" +
+		"........-n,.....--name...Some name\n" +
+		"................--force..Force it\n" +
+		"\n\n\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestHelpFunc_Constraints(t *testing.T) {
+	var options struct {
+		Create bool   `goptions:"--create, mutexgroup='action'"`
+		Delete bool   `goptions:"--delete, mutexgroup='action'"`
+		Server string `goptions:"--server, obligatory-for='deploy'"`
+
+		Verbs
+		Deploy struct{} `goptions:"deploy"`
+	}
+	t.Setenv("COLUMNS", "")
+	fs := NewFlagSet("goptions", &options)
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	expected := "Constraints:\n" +
+		"    At most one of --create, --delete\n" +
+		"    --server is required by deploy\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("Constraints not rendered in help: %q", buf.String())
+	}
+}
+
+func TestPrintHelpForVerb(t *testing.T) {
+	var options struct {
+		Verbose bool `goptions:"-v, --verbose, description='Be verbose'"`
+
+		Verbs
+		Delete struct {
+			Force bool `goptions:"-f, --force, description='Force removal'"`
+		} `goptions:"delete, rm"`
+	}
+	t.Setenv("COLUMNS", "")
+	fs := NewFlagSet("goptions", &options)
+
+	var buf bytes.Buffer
+	err := fs.PrintHelpForVerb(&buf, "rm")
+	if err != nil {
+		t.Fatalf("Printing help failed: %s", err)
+	}
+	help := buf.String()
+	if !strings.HasPrefix(help, "Usage: goptions delete ") ||
+		!strings.Contains(help, "--force Force removal") ||
+		strings.Contains(help, "--verbose") {
+		t.Fatalf("Unexpected help: %q", help)
+	}
+
+	err = fs.PrintHelpForVerb(&buf, "unknown")
+	if err == nil {
+		t.Fatalf("Printing help of an unknown verb should have failed")
+	}
+}
+
+func TestHelpFunc_SelectedVerb(t *testing.T) {
+	var options struct {
+		Help Help `goptions:"-h, --help"`
+
+		Verbs
+		Delete struct {
+			Help  Help `goptions:"-h, --help"`
+			Force bool `goptions:"-f, --force"`
+		} `goptions:"delete, rm"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	var verb string
+	fs.HelpFunc = func(w io.Writer, fs *FlagSet) {
+		verb = fs.SelectedVerb()
+	}
+
+	err := fs.Parse([]string{"rm", "--help"})
+	if err != ErrHelpRequest {
+		t.Fatalf("Expected ErrHelpRequest, got: %v", err)
+	}
+	fs.PrintHelp(ioutil.Discard)
+	if verb != "delete" {
+		t.Fatalf("Unexpected verb: %#v", verb)
+	}
+}
+
+func TestHelpFunc_CurrentDefaults(t *testing.T) {
+	var options struct {
+		Name    string `goptions:"-n, --name, description='Some name'"`
+		Retries int    `goptions:"--retries, description='Number of retries'"`
+		Force   bool   `goptions:"-f, --force, description='Force'"`
+	}
+	options.Name = "preset"
+	fs := NewFlagSet("goptions", &options)
+	options.Retries = 3
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	help := buf.String()
+	if !strings.Contains(help, "Some name (default: preset)") ||
+		!strings.Contains(help, "Number of retries (default: 3)") ||
+		strings.Contains(help, "Force (default") {
+		t.Fatalf("Unexpected help: %q", help)
+	}
+
+	err := fs.Parse([]string{"--name", "given"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	buf.Reset()
+	fs.PrintHelp(&buf)
+	if !strings.Contains(buf.String(), "Some name (default: preset)") {
+		t.Fatalf("Unexpected help: %q", buf.String())
+	}
+}
+
+func TestHelpFunc_ZeroTimeDefault(t *testing.T) {
+	var options struct {
+		Since time.Time `goptions:"--since, description='Start date'"`
+		Until time.Time `goptions:"--until, description='End date', default='2024-01-02T00:00:00Z'"`
+	}
+	t.Setenv("COLUMNS", "")
+	fs := NewFlagSet("goptions", &options)
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	help := buf.String()
+	if strings.Contains(help, "Start date (default") || !strings.Contains(help, "End date (default: 2024-01-02 00:00:00 +0000 UTC)") {
+		t.Fatalf("Unexpected help: %q", help)
+	}
+}
+
+func TestHelpFunc_ZeroStructDefault(t *testing.T) {
+	var options struct {
+		Mount mount `goptions:"-m, --mount, description='Mount point'"`
+	}
+	t.Setenv("COLUMNS", "")
+	fs := NewFlagSet("goptions", &options)
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	help := buf.String()
+	if strings.Contains(help, "Mount point (default") {
+		t.Fatalf("Unexpected help: %q", help)
+	}
+}
+
+func TestHelpFunc_Compact(t *testing.T) {
+	var options struct {
+		Name  string `goptions:"-n, --name, obligatory, description='Some name'"`
+		Force bool   `goptions:"-f, --force, description='Force'"`
+		Quiet bool   `goptions:"--quiet"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	t.Setenv("COLUMNS", "40")
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	expected := "Global options:\n" +
+		"  -n, --name (*)\n" +
+		"      Some name\n" +
+		"  -f, --force\n" +
+		"      Force\n" +
+		"  --quiet\n\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("Unexpected help: %q", buf.String())
+	}
+}
+
+func TestHelpFunc_HelpWidth(t *testing.T) {
+	var options struct {
+		Name string `goptions:"-n, --name, description='The name of the thing to create', default='thing'"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	fs.HelpWidth = 30
+
+	t.Setenv("COLUMNS", "200")
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	expected := "Global options:\n" +
+		"  -n, --name\n" +
+		"      The name of the thing to\n" +
+		"      create (default: thing)\n\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("Unexpected help: %q", buf.String())
+	}
+}
+
+func TestHelpFunc_HelpWidthTabwriter(t *testing.T) {
+	var options struct {
+		Name    string `goptions:"-n, --name, description='The name of the thing to create, which has to be unique within the project', default='thing'"`
+		Verbose bool   `goptions:"-v, --verbose, description='Be verbose'"`
+	}
+	t.Setenv("COLUMNS", "")
+	fs := NewFlagSet("goptions", &options)
+	fs.HelpWidth = 60
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	expected := "Global options:\n" +
+		"    -n, --name    The name of the thing to create, which has\n" +
+		"                  to be unique within the project (default:\n" +
+		"                  thing)\n" +
+		"    -v, --verbose Be verbose\n\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("Unexpected help: %q", buf.String())
+	}
+
+	fs.HelpWidth = 0
+	buf.Reset()
+	fs.PrintHelp(&buf)
+	if !strings.Contains(buf.String(), "create, which has to be unique within the project (default: thing)\n") {
+		t.Fatalf("Unexpected unwrapped help: %q", buf.String())
+	}
+}
+
+func TestHelpFunc_Example(t *testing.T) {
+	var options struct {
+		Date string `goptions:"--date, description='Day of the report', example='--date 2023-01-31'"`
+	}
+	t.Setenv("COLUMNS", "")
+	fs := NewFlagSet("goptions", &options)
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	if !strings.Contains(buf.String(), "Day of the report (e.g. --date 2023-01-31)\n") {
+		t.Fatalf("Unexpected help: %q", buf.String())
+	}
+
+	t.Setenv("COLUMNS", "40")
+	buf.Reset()
+	fs.PrintHelp(&buf)
+	expected := "  --date\n" +
+		"      Day of the report (e.g. --date\n" +
+		"      2023-01-31)\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("Unexpected compact help: %q", buf.String())
+	}
+}
+
+func TestHelpFunc_Repeatable(t *testing.T) {
+	var options struct {
+		Verbosity Counter  `goptions:"-v, --verbose, description='Verbosity'"`
+		Retries   int      `goptions:"--retries, accumulate, description='Retries'"`
+		Hosts     []string `goptions:"--host, description='Hosts'"`
+		Name      string   `goptions:"--name, description='Name'"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	fs.HelpFunc = NewTemplatedHelpFunc(_DEFAULT_HELP)
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	help := buf.String()
+	for _, expected := range []string{
+		"--verbose\tVerbosity (repeatable)\n",
+		"--retries\tRetries (repeatable)\n",
+		"--host\tHosts (can be set multiple times)\n",
+		"--name\tName\n",
+	} {
+		if !strings.Contains(help, expected) {
+			t.Fatalf("Unexpected help, missing %q: %q", expected, help)
+		}
+	}
+}
+
+func TestHelpFunc_ObligatoryMarker(t *testing.T) {
+	var options struct {
+		Name  string `goptions:"-n, --name, obligatory, description='Some name'"`
+		Force bool   `goptions:"-f, --force, description='Force'"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	fs.ObligatoryMarker = "[required]"
+	fs.HelpFunc = NewTemplatedHelpFunc(_DEFAULT_HELP)
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	help := buf.String()
+	if !strings.Contains(help, "\tSome name [required]\n") ||
+		!strings.Contains(help, "\tForce\n") ||
+		!strings.Contains(help, "\n[required] marks obligatory flags\n") {
+		t.Fatalf("Unexpected help: %q", help)
+	}
+
+	var noObligatory struct {
+		Force bool `goptions:"-f, --force"`
+	}
+	buf.Reset()
+	NewFlagSet("goptions", &noObligatory).PrintHelp(&buf)
+	if strings.Contains(buf.String(), "marks obligatory flags") {
+		t.Fatalf("Unexpected legend: %q", buf.String())
+	}
+}
+
+func TestHelpFunc_ObligatoryLegendAbsent(t *testing.T) {
+	var noObligatory struct {
+		Force bool `goptions:"-f, --force, description='Force'"`
+		Verbs
+		Remove struct {
+			Name string `goptions:"--name, description='Name to remove'"`
+		} `goptions:"remove"`
+	}
+	for _, helpFunc := range []HelpFunc{NewTemplatedHelpFunc(_DEFAULT_HELP), CompactHelpFunc} {
+		fs := NewFlagSet("goptions", &noObligatory)
+		fs.HelpFunc = helpFunc
+		var buf bytes.Buffer
+		fs.PrintHelp(&buf)
+		if strings.Contains(buf.String(), "(*)") || strings.Contains(buf.String(), "marks obligatory flags") {
+			t.Fatalf("Unexpected legend: %q", buf.String())
+		}
+	}
+
+	var options struct {
+		Force bool `goptions:"-f, --force, description='Force'"`
+		Verbs
+		Remote struct {
+			Verbs
+			Add struct {
+				Url string `goptions:"--url, obligatory"`
+			} `goptions:"add"`
+		} `goptions:"remote"`
+	}
+	for _, helpFunc := range []HelpFunc{NewTemplatedHelpFunc(_DEFAULT_HELP), CompactHelpFunc} {
+		fs := NewFlagSet("goptions", &options)
+		fs.HelpFunc = helpFunc
+		var buf bytes.Buffer
+		fs.PrintHelp(&buf)
+		help := buf.String()
+		if strings.Contains(help, "(*)") {
+			t.Fatalf("Unexpected legend: %q", help)
+		}
+
+		buf.Reset()
+		err := fs.PrintHelpForVerb(&buf, "remote")
+		if err != nil {
+			t.Fatalf("Printing help failed: %s", err)
+		}
+		if !strings.Contains(buf.String(), "(*) marks obligatory flags") {
+			t.Fatalf("Missing legend: %q", buf.String())
+		}
+	}
+}
+
+func TestHelpFunc_SortFlags(t *testing.T) {
+	var options struct {
+		Verbose bool   `goptions:"-v, --verbose"`
+		Name    string `goptions:"--name"`
+		Force   bool   `goptions:"-f"`
+		All     bool   `goptions:"-a, --all"`
+	}
+	order := func(help string, names ...string) bool {
+		last := -1
+		for _, name := range names {
+			idx := strings.Index(help, name)
+			if idx <= last {
+				return false
+			}
+			last = idx
+		}
+		return true
+	}
+
+	fs := NewFlagSet("goptions", &options)
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	if !order(buf.String(), "--verbose", "--name", "-f", "--all") {
+		t.Fatalf("Unexpected help: %q", buf.String())
+	}
+
+	fs.SortFlags = true
+	buf.Reset()
+	fs.PrintHelp(&buf)
+	if !order(buf.String(), "--all", "-f", "--name", "--verbose") {
+		t.Fatalf("Unexpected sorted help: %q", buf.String())
+	}
+	if fs.Flags[0].Long != "verbose" {
+		t.Fatalf("Unexpected order of flags: %#v", fs.Flags)
+	}
+}
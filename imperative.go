@@ -0,0 +1,149 @@
+package goptions
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NewFlag returns a new Flag for the variable v points to. The tag has the
+// same format as the tags of struct members.
+func NewFlag(v interface{}, tag string) (*Flag, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("Value type is not a pointer")
+	}
+	return parseStructField(value.Elem(), tag)
+}
+
+// AddFlag adds a flag to the FlagSet. An error is returned if one of the
+// flag's names is already used by another flag of the FlagSet.
+func (fs *FlagSet) AddFlag(f *Flag) error {
+	err := fs.checkCollision(f)
+	if err != nil {
+		return err
+	}
+	if greedy, _ := f.optionMeta["greedy"].(bool); greedy {
+		if fs.greedyFlag != nil {
+			return fmt.Errorf("Multiple greedy flags: %s, %s", fs.greedyFlag.Name(), f.Name())
+		}
+		fs.greedyFlag = f
+	}
+	f.flagSet = fs
+	fs.Flags = append(fs.Flags, f)
+	fs.createMaps()
+	return nil
+}
+
+func (fs *FlagSet) checkCollision(f *Flag) error {
+	names := append([]string{f.Long}, f.NegatedLongs()...)
+	names = append(names, f.aliases...)
+	for _, name := range names {
+		if len(name) > 0 && fs.hasLongFlag(name) {
+			return fmt.Errorf("Flag --%s already exists", name)
+		}
+	}
+	if len(f.Short) > 0 && fs.hasShortFlag(f.Short) {
+		return fmt.Errorf("Flag -%s already exists", f.Short)
+	}
+	return nil
+}
+
+// Alias makes the long name newName refer to the existing flag, e.g. to
+// keep the old name of a renamed flag working. Both names can be given with
+// or without leading dashes. An error is returned if newName is taken.
+func (fs *FlagSet) Alias(existing, newName string) error {
+	f, err := fs.lookupFlag(existing)
+	if err != nil {
+		return err
+	}
+	newName = strings.TrimPrefix(newName, "--")
+	if len(newName) == 0 || strings.HasPrefix(newName, "-") {
+		return fmt.Errorf("Invalid alias --%s", newName)
+	}
+	if fs.hasLongFlag(newName) {
+		return fmt.Errorf("Flag --%s already exists", newName)
+	}
+	f.aliases = append(append([]string{}, f.aliases...), newName)
+	fs.createMaps()
+	return nil
+}
+
+// Merge adds the flags and verbs of other to the FlagSet. If a flag or a
+// verb of other collides with one of the FlagSet, an error is returned and
+// the FlagSet is left unchanged.
+func (fs *FlagSet) Merge(other *FlagSet) error {
+	for _, f := range other.Flags {
+		err := fs.checkCollision(f)
+		if err != nil {
+			return err
+		}
+	}
+	if fs.greedyFlag != nil && other.greedyFlag != nil {
+		return fmt.Errorf("Multiple greedy flags: %s, %s", fs.greedyFlag.Name(), other.greedyFlag.Name())
+	}
+	for name, verb := range other.Verbs {
+		for _, name := range append([]string{name}, verb.Aliases...) {
+			if _, ok := fs.verbByName(name); ok {
+				return fmt.Errorf("Verb %s already exists", name)
+			}
+		}
+	}
+
+	for _, f := range other.Flags {
+		f.flagSet = fs
+		fs.Flags = append(fs.Flags, f)
+	}
+	if fs.greedyFlag == nil {
+		fs.greedyFlag = other.greedyFlag
+	}
+	if fs.remainderFlag == nil {
+		fs.remainderFlag = other.remainderFlag
+	}
+	if fs.verbFlag == nil {
+		fs.verbFlag = other.verbFlag
+	}
+	if len(other.Verbs) > 0 && fs.Verbs == nil {
+		fs.Verbs = make(map[string]*FlagSet)
+	}
+	for name, verb := range other.Verbs {
+		verb.parent = fs
+		fs.Verbs[name] = verb
+	}
+	fs.createMaps()
+	return nil
+}
+
+// Var adds a flag for the variable v points to. If the tag is invalid or
+// the flag cannot be added, Var panics.
+func (fs *FlagSet) Var(v interface{}, tag string) {
+	f, err := NewFlag(v, tag)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid flag: %s", err))
+	}
+	err = fs.AddFlag(f)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid flag: %s", err))
+	}
+}
+
+// Bool adds a bool flag and returns a pointer to its value.
+func (fs *FlagSet) Bool(tag string) *bool {
+	v := new(bool)
+	fs.Var(v, tag)
+	return v
+}
+
+// String adds a string flag and returns a pointer to its value.
+func (fs *FlagSet) String(tag string) *string {
+	v := new(string)
+	fs.Var(v, tag)
+	return v
+}
+
+// Int adds an int flag and returns a pointer to its value.
+func (fs *FlagSet) Int(tag string) *int {
+	v := new(int)
+	fs.Var(v, tag)
+	return v
+}
@@ -0,0 +1,189 @@
+package goptions
+
+import (
+	"testing"
+)
+
+func TestImperative(t *testing.T) {
+	var args []string
+	var err error
+	fs := NewFlagSet("goptions", &struct{}{})
+	name := fs.String("-n, --name, obligatory")
+	force := fs.Bool("-f, --force")
+	limit := fs.Int("--limit")
+	var servers []string
+	fs.Var(&servers, "-s, --server")
+
+	args = []string{"-f", "--limit", "5", "-n", "SomeName", "-s", "a", "-s", "b"}
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(*name == "SomeName" &&
+		*force &&
+		*limit == 5 &&
+		len(servers) == 2) {
+		t.Fatalf("Unexpected value: %v %v %v %v", *name, *force, *limit, servers)
+	}
+
+	fs = NewFlagSet("goptions", &struct{}{})
+	fs.String("-n, --name, obligatory")
+	err = fs.Parse([]string{})
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestImperative_Collision(t *testing.T) {
+	fs := NewFlagSet("goptions", &struct{}{})
+	fs.String("-n, --name")
+
+	f, err := NewFlag(new(string), "--name")
+	if err != nil {
+		t.Fatalf("Creating flag failed: %s", err)
+	}
+	if fs.AddFlag(f) == nil {
+		t.Fatalf("Adding --name twice should have failed")
+	}
+	f, _ = NewFlag(new(bool), "-n, --nothing")
+	if fs.AddFlag(f) == nil {
+		t.Fatalf("Adding -n twice should have failed")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var args []string
+	var err error
+	var base struct {
+		Verbose bool `goptions:"-v, --verbose"`
+
+		Verbs
+		Build struct {
+			Target string `goptions:"--target"`
+		} `goptions:"build"`
+	}
+	var plugin struct {
+		Token string `goptions:"--token, obligatory"`
+
+		Verbs
+		Deploy struct {
+			Force bool `goptions:"--force"`
+		} `goptions:"deploy"`
+	}
+	fs := NewFlagSet("goptions", &base)
+	err = fs.Merge(NewFlagSet("plugin", &plugin))
+	if err != nil {
+		t.Fatalf("Merging failed: %s", err)
+	}
+
+	args = []string{"-v", "--token", "secret", "deploy", "--force"}
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(base.Verbose &&
+		plugin.Token == "secret" &&
+		plugin.Deploy.Force &&
+		base.Verbs == "deploy") {
+		t.Fatalf("Unexpected value: %#v %#v", base, plugin)
+	}
+
+	fs = NewFlagSet("goptions", &base)
+	fs.Merge(NewFlagSet("plugin", &plugin))
+	err = fs.Parse([]string{"build"})
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestMerge_Collision(t *testing.T) {
+	var base struct {
+		Verbose bool `goptions:"-v, --verbose"`
+
+		Verbs
+		Build struct{} `goptions:"build"`
+	}
+	var flags struct {
+		Version bool `goptions:"-v, --version"`
+	}
+	var verbs struct {
+		Verbs
+		Build struct{} `goptions:"compile, build"`
+	}
+	fs := NewFlagSet("goptions", &base)
+	if fs.Merge(NewFlagSet("plugin", &flags)) == nil {
+		t.Fatalf("Merging colliding flags should have failed")
+	}
+	if fs.Merge(NewFlagSet("plugin", &verbs)) == nil {
+		t.Fatalf("Merging colliding verbs should have failed")
+	}
+	if len(fs.Flags) != 1 || len(fs.Verbs) != 1 {
+		t.Fatalf("FlagSet has been modified: %#v", fs)
+	}
+}
+
+func TestSet(t *testing.T) {
+	var err error
+	var options struct {
+		Name  string   `goptions:"-n, --name"`
+		Mode  string   `goptions:"--mode, choices='fast,slow'"`
+		Hosts []string `goptions:"--host"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	err = fs.Set("--name", "alice")
+	if err != nil {
+		t.Fatalf("Setting failed: %s", err)
+	}
+	err = fs.Set("host", "a")
+	if err != nil {
+		t.Fatalf("Setting failed: %s", err)
+	}
+	err = fs.Set("-n", "bob")
+	if err != nil {
+		t.Fatalf("Setting failed: %s", err)
+	}
+	if options.Name != "bob" || len(options.Hosts) != 1 || options.Hosts[0] != "a" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+	if src, _ := fs.Source("name"); src != SourceCLI || !fs.FlagByName("-n").WasSpecified {
+		t.Fatalf("Unexpected source: %s", src)
+	}
+
+	if err = fs.Set("mode", "medium"); err == nil {
+		t.Fatalf("Setting an invalid choice should have failed")
+	}
+	if err = fs.Set("unknown", "x"); err == nil {
+		t.Fatalf("Setting an unknown flag should have failed")
+	}
+}
+
+func TestAlias(t *testing.T) {
+	var args []string
+	var err error
+	var options struct {
+		Output string `goptions:"-o, --output"`
+		Force  bool   `goptions:"--force"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	err = fs.Alias("--output", "--out-file")
+	if err != nil {
+		t.Fatalf("Adding alias failed: %s", err)
+	}
+	if err = fs.Alias("output", "force"); err == nil {
+		t.Fatalf("Colliding alias should have failed")
+	}
+	if err = fs.Alias("unknown", "other"); err == nil {
+		t.Fatalf("Alias of an unknown flag should have failed")
+	}
+
+	args = []string{"--out-file", "a.txt"}
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Output != "a.txt" || !fs.FlagByName("--output").Handles("--out-file=b.txt") {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
@@ -0,0 +1,117 @@
+package goptions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Matches `${name}` and the escaped form `$${name}`
+	interpolationRegexp = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+)
+
+// interpolatedDefault returns the default of the flag if it references
+// other flags with `${name}`.
+func (f *Flag) interpolatedDefault() (string, bool) {
+	def, ok := f.optionMeta["default"].(string)
+	if !ok {
+		return "", false
+	}
+	for _, ref := range interpolationRegexp.FindAllString(def, -1) {
+		if !isEscapedReference(ref) {
+			return def, true
+		}
+	}
+	return "", false
+}
+
+// isEscapedReference returns true for a match of interpolationRegexp
+// which stands for a literal `${`.
+func isEscapedReference(ref string) bool {
+	return strings.HasPrefix(ref, "$$")
+}
+
+// unescapeReferences replaces the escaped references `$${name}` in def
+// with the literal `${name}`.
+func unescapeReferences(def string) string {
+	return interpolationRegexp.ReplaceAllStringFunc(def, func(ref string) string {
+		if isEscapedReference(ref) {
+			return ref[1:]
+		}
+		return ref
+	})
+}
+
+// applyInterpolatedDefaults sets the flags whose defaults reference other
+// flags and which have not been given a value otherwise.
+func (fs *FlagSet) applyInterpolatedDefaults() error {
+	for _, f := range fs.Flags {
+		_, err := f.resolve(nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the final value of the flag as a string, applying its
+// interpolated default first if necessary. path holds the flags whose
+// defaults are being resolved to detect cycles.
+func (f *Flag) resolve(path []*Flag) (string, error) {
+	def, ok := f.interpolatedDefault()
+	if !ok || f.source != SourceUnset {
+		return fmt.Sprint(f.value.Interface()), nil
+	}
+	for i, p := range path {
+		if p == f {
+			names := make([]string, 0, len(path)-i+1)
+			for _, p := range path[i:] {
+				names = append(names, p.Name())
+			}
+			names = append(names, f.Name())
+			return "", fmt.Errorf("Cycle in defaults: %s", strings.Join(names, " -> "))
+		}
+	}
+	path = append(path, f)
+
+	var err error
+	value := interpolationRegexp.ReplaceAllStringFunc(def, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		if isEscapedReference(ref) {
+			return ref[1:]
+		}
+		name := interpolationRegexp.FindStringSubmatch(ref)[1]
+		other := f.flagSet.lookupInterpolated(name)
+		if other == nil {
+			err = fmt.Errorf("Unknown flag %s in default of %s", name, f.Name())
+			return ""
+		}
+		var s string
+		s, err = other.resolve(path)
+		return s
+	})
+	if err != nil {
+		return "", err
+	}
+	err = f.setValue(value)
+	if err != nil {
+		return "", fmt.Errorf("Invalid default value for %s: %s", f.Name(), err)
+	}
+	f.source = SourceDefault
+	return fmt.Sprint(f.value.Interface()), nil
+}
+
+// lookupInterpolated returns the flag referenced by name in an
+// interpolated default. Flags of the parent FlagSets can be referenced
+// from verbs.
+func (fs *FlagSet) lookupInterpolated(name string) *Flag {
+	for ; fs != nil; fs = fs.parent {
+		if f, err := fs.lookupFlag(name); err == nil {
+			return f
+		}
+	}
+	return nil
+}
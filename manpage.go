@@ -0,0 +1,96 @@
+package goptions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteManPage writes a basic man page of the FlagSet in troff format to
+// the given writer. The page has the NAME, SYNOPSIS, DESCRIPTION, OPTIONS
+// and VERBS sections, the latter two listing the flags with their
+// metavars and descriptions.
+func (fs *FlagSet) WriteManPage(w io.Writer, section int) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, ".TH %s %d\n", troffEscape(strings.ToUpper(fs.ProgramName())), section)
+	fmt.Fprintf(&b, ".SH NAME\n%s", troffEscape(fs.ProgramName()))
+	if fs.Description != "" {
+		fmt.Fprintf(&b, " \\- %s", troffEscape(strings.SplitN(fs.Description, "\n", 2)[0]))
+	}
+	fmt.Fprintf(&b, "\n.SH SYNOPSIS\n%s\n", troffEscape(fs.Synopsis()))
+	if fs.Description != "" {
+		fmt.Fprintf(&b, ".SH DESCRIPTION\n%s\n", troffText(fs.Description))
+	}
+	if len(fs.Flags) > 0 {
+		b.WriteString(".SH OPTIONS\n")
+		writeManFlags(&b, fs.Flags)
+	}
+	if len(fs.Verbs) > 0 {
+		b.WriteString(".SH VERBS\n")
+		writeManVerbs(&b, fs)
+	}
+	if fs.Epilog != "" {
+		fmt.Fprintf(&b, ".SH NOTES\n%s\n", troffText(fs.Epilog))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeManVerbs(b *bytes.Buffer, fs *FlagSet) {
+	names := make([]string, 0, len(fs.Verbs))
+	for name := range fs.Verbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		verb := fs.Verbs[name]
+		fmt.Fprintf(b, ".SS %s\n", troffEscape(verb.FullName()))
+		if verb.Description != "" {
+			fmt.Fprintf(b, "%s\n", troffText(verb.Description))
+		}
+		writeManFlags(b, verb.Flags)
+		writeManVerbs(b, verb)
+	}
+}
+
+func writeManFlags(b *bytes.Buffer, flags []*Flag) {
+	for _, f := range flags {
+		names := make([]string, 0, 2)
+		if f.Short != "" {
+			names = append(names, `\fB\-`+troffEscape(f.Short)+`\fR`)
+		}
+		if f.Long != "" {
+			names = append(names, `\fB\-\-`+troffEscape(f.Long)+`\fR`)
+		}
+		fmt.Fprintf(b, ".TP\n%s", strings.Join(names, ", "))
+		if f.NeedsExtraValue() {
+			fmt.Fprintf(b, ` \fI%s\fR`, troffEscape(f.Metavar()))
+		}
+		b.WriteString("\n")
+		description := f.Description
+		if f.Obligatory {
+			description += " (required)"
+		}
+		fmt.Fprintf(b, "%s\n", troffText(strings.TrimSpace(description)))
+	}
+}
+
+// troffEscape escapes backslashes and dashes for troff.
+func troffEscape(s string) string {
+	s = strings.Replace(s, `\`, `\e`, -1)
+	return strings.Replace(s, "-", `\-`, -1)
+}
+
+// troffText escapes a block of text for troff, making sure that no line
+// is mistaken for a request.
+func troffText(s string) string {
+	lines := strings.Split(troffEscape(s), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,45 @@
+package goptions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteManPage(t *testing.T) {
+	var options struct {
+		Server  string `goptions:"-s, --server, obligatory, metavar='HOST', description='Server to connect to'"`
+		Verbose bool   `goptions:"-v, --verbose, description='Be verbose'"`
+
+		Verbs
+		Delete struct {
+			Force bool `goptions:"-f, --force, description='Force removal'"`
+		} `goptions:"delete"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	fs.SetDescription("Parse command line flags")
+
+	var buf bytes.Buffer
+	err := fs.WriteManPage(&buf, 1)
+	if err != nil {
+		t.Fatalf("Writing man page failed: %s", err)
+	}
+	man := buf.String()
+	if !strings.HasPrefix(man, ".TH GOPTIONS 1\n") {
+		t.Fatalf("Unexpected man page header:\n%s", man)
+	}
+	expected := []string{
+		".SH NAME\ngoptions \\- Parse command line flags\n",
+		".SH SYNOPSIS\ngoptions \\-\\-server HOST [\\-\\-verbose] <verb> [verb options]\n",
+		".SH OPTIONS\n",
+		".TP\n\\fB\\-s\\fR, \\fB\\-\\-server\\fR \\fIHOST\\fR\nServer to connect to (required)\n",
+		".TP\n\\fB\\-v\\fR, \\fB\\-\\-verbose\\fR\nBe verbose\n",
+		".SS goptions delete\n",
+		".TP\n\\fB\\-f\\fR, \\fB\\-\\-force\\fR\nForce removal\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(man, e) {
+			t.Fatalf("Missing %q in man page:\n%s", e, man)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package goptions
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+const _MARKDOWN = `{{define "flags"}}| Flag | Type | Default | Description |
+| --- | --- | --- | --- |
+{{range .}}| {{with .Short}}` + "`-{{.}}`" + `{{end}}{{if and .Short .Long}}, {{end}}{{with .Long}}` + "`--{{.}}`" + `{{end}} | {{type .}} | {{with .Default}}{{cell .}}{{end}} | {{cell .Description}}{{if .Obligatory}} (required){{end}} |
+{{end}}{{end}}{{define "verbs"}}{{range .Verbs}}
+## {{.FullName}}
+{{with .Description}}
+{{.}}
+{{end}}{{with .Flags}}
+{{template "flags" .}}{{end}}{{template "verbs" .}}{{end}}{{end}}# {{.FullName}}
+{{with .Description}}
+{{.}}
+{{end}}
+## Global options
+
+{{template "flags" .Flags}}{{template "verbs" .}}`
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"type": func(f *Flag) string {
+		return f.value.Type().String()
+	},
+	"cell": func(v interface{}) string {
+		return strings.Replace(fmt.Sprint(v), "|", `\|`, -1)
+	},
+}).Parse(_MARKDOWN))
+
+// WriteMarkdown writes the documentation of the FlagSet and its verbs as
+// a Markdown document to the given writer. The flags are rendered as
+// tables with their names, types, defaults and descriptions.
+func (fs *FlagSet) WriteMarkdown(w io.Writer) error {
+	return markdownTemplate.Execute(w, fs)
+}
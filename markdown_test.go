@@ -0,0 +1,40 @@
+package goptions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	var options struct {
+		Server  string `goptions:"-s, --server, obligatory, description='Server to connect to'"`
+		Timeout int    `goptions:"--timeout, default='10', description='Timeout in seconds'"`
+
+		Verbs
+		Delete struct {
+			Force bool `goptions:"-f, --force, description='Force removal'"`
+		} `goptions:"delete"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	var buf bytes.Buffer
+	err := fs.WriteMarkdown(&buf)
+	if err != nil {
+		t.Fatalf("Writing Markdown failed: %s", err)
+	}
+	md := buf.String()
+	expected := []string{
+		"# goptions\n",
+		"| Flag | Type | Default | Description |\n| --- | --- | --- | --- |\n",
+		"| `-s`, `--server` | string |  | Server to connect to (required) |\n",
+		"| `--timeout` | int | 10 | Timeout in seconds |\n",
+		"## goptions delete\n",
+		"| `-f`, `--force` | bool |  | Force removal |\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(md, e) {
+			t.Fatalf("Missing %q in Markdown:\n%s", e, md)
+		}
+	}
+}
@@ -39,3 +39,48 @@ func TestMarshaler(t *testing.T) {
 		t.Fatalf("Unexpected value: %#v", options)
 	}
 }
+
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+func (kv *KeyValue) MarshalGoption(val string) error {
+	f := strings.SplitN(val, "=", 2)
+	if len(f) != 2 {
+		return fmt.Errorf("Missing =")
+	}
+	kv.Key = f[0]
+	kv.Value = f[1]
+	return nil
+}
+
+func TestMarshaler_Slice(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Labels []KeyValue  `goptions:"--label"`
+		Names  []*KeyValue `goptions:"--name"`
+	}
+	args = []string{"--label", "a=1", "--name", "x=y", "--label", "b=2"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(len(options.Labels) == 2 &&
+		options.Labels[0] == KeyValue{"a", "1"} &&
+		options.Labels[1] == KeyValue{"b", "2"} &&
+		len(options.Names) == 1 &&
+		*options.Names[0] == KeyValue{"x", "y"}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--label", "a"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
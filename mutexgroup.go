@@ -1,5 +1,10 @@
 package goptions
 
+import (
+	"fmt"
+	"strings"
+)
+
 // A MutexGroup holds a set of flags which are mutually exclusive and cannot
 // be specified at the same time.
 type MutexGroup []*Flag
@@ -46,3 +51,21 @@ func (mg MutexGroup) Names() []string {
 	}
 	return r
 }
+
+// ErrMutexGroupViolation is returned by Parse() if the flags of a MutexGroup
+// have not been specified correctly.
+type ErrMutexGroupViolation struct {
+	// Name of the MutexGroup
+	Name  string
+	Group MutexGroup
+	// NoneSpecified is true if no flag of an obligatory MutexGroup has
+	// been specified and false if more than one flag has been specified.
+	NoneSpecified bool
+}
+
+func (e *ErrMutexGroupViolation) Error() string {
+	if e.NoneSpecified {
+		return fmt.Sprintf("One of %s must be specified", strings.Join(e.Group.Names(), ", "))
+	}
+	return fmt.Sprintf("Only one of %s can be specified", strings.Join(e.Group.Names(), ", "))
+}
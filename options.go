@@ -2,10 +2,12 @@ package goptions
 
 import (
 	"fmt"
+	"math/big"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type optionFunc func(f *Flag, option, value string) error
@@ -15,9 +17,56 @@ var (
 	typeOptionMap = map[reflect.Type]optionMap{
 		// Global options
 		nil: optionMap{
-			"description": description,
-			"obligatory":  obligatory,
-			"mutexgroup":  mutexgroup,
+			"description":      description,
+			"obligatory":       obligatory,
+			"mutexgroup":       mutexgroup,
+			"default":          defaultValue,
+			"min":              sliceLimit,
+			"max":              sliceLimit,
+			"choices":          choices,
+			"choices-ci":       choicesCaseInsensitive,
+			"choices-func":     choicesFunc,
+			"error":            errorMessage,
+			"secret":           secret,
+			"greedy":           greedy,
+			"transform":        transform,
+			"validate":         validate,
+			"allow-dash-value": allowDashValue,
+			"obligatory-for":   obligatoryFor,
+			"obligatory-if":    obligatoryIf,
+			"env":              env,
+			"positional":       positional,
+			"metavar":          metavar,
+			"synopsis":         synopsis,
+			"example":          example,
+			"deprecated":       deprecated,
+			"override":         override,
+			"accumulate":       accumulate,
+			"reduce":           reduce,
+			"replace-on-set":   replaceOnSet,
+			"delim":            delim,
+			"group-all":        groupAll,
+			"prompt":           prompt,
+		},
+		reflect.TypeOf(new(bool)).Elem(): optionMap{
+			"negatable":     negatable,
+			"negate-prefix": negatePrefix,
+			"off-suffix":    offSuffix,
+		},
+		reflect.TypeOf(new(float64)).Elem(): optionMap{
+			"allow-nonfinite": allowNonfinite,
+		},
+		reflect.TypeOf(new([]float64)).Elem(): optionMap{
+			"allow-nonfinite": allowNonfinite,
+		},
+		reflect.TypeOf(new(*big.Float)).Elem(): optionMap{
+			"allow-nonfinite": allowNonfinite,
+		},
+		reflect.TypeOf(new(time.Duration)).Elem(): optionMap{
+			"unit": duration_unit,
+		},
+		reflect.TypeOf(new(time.Time)).Elem(): optionMap{
+			"layout": timeLayout,
 		},
 		reflect.TypeOf(new(*os.File)).Elem(): optionMap{
 			"create": initOptionMeta(file_create, "file_mode", 0),
@@ -49,11 +98,200 @@ func description(f *Flag, option, value string) error {
 	return nil
 }
 
+func errorMessage(f *Flag, option, value string) error {
+	f.ErrorMessage = strings.Replace(value, `\`, ``, -1)
+	return nil
+}
+
 func obligatory(f *Flag, option, value string) error {
 	f.Obligatory = true
 	return nil
 }
 
+func secret(f *Flag, option, value string) error {
+	f.Secret = true
+	return nil
+}
+
+func greedy(f *Flag, option, value string) error {
+	if f.value.Kind() != reflect.Slice && f.value.Kind() != reflect.String {
+		return fmt.Errorf("Only strings and slices can be greedy")
+	}
+	f.optionMeta["greedy"] = true
+	return nil
+}
+
+func transform(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Transform option needs a value")
+	}
+	transforms, _ := f.optionMeta["transforms"].([]TransformFunc)
+	for _, name := range strings.Split(value, ",") {
+		fn, ok := transformMap[name]
+		if !ok {
+			return fmt.Errorf("Unknown transform %s", name)
+		}
+		transforms = append(transforms, fn)
+	}
+	f.optionMeta["transforms"] = transforms
+	return nil
+}
+
+func allowDashValue(f *Flag, option, value string) error {
+	f.optionMeta["allow_dash_value"] = true
+	return nil
+}
+
+func obligatoryFor(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Obligatory-for option needs a value")
+	}
+	verbs := strings.Split(value, ",")
+	for i := range verbs {
+		verbs[i] = strings.TrimSpace(verbs[i])
+	}
+	f.optionMeta["obligatory_for"] = verbs
+	return nil
+}
+
+// A condition holds if the flag with the long name has the value.
+type condition struct {
+	name, value string
+}
+
+func obligatoryIf(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Obligatory-if option needs a value")
+	}
+	conditions, _ := f.optionMeta["obligatory_if"].([]condition)
+	for _, c := range strings.Split(value, ",") {
+		idx := strings.Index(c, "=")
+		if idx <= 0 {
+			return fmt.Errorf("Invalid condition %s, must be name=value", c)
+		}
+		conditions = append(conditions, condition{strings.TrimSpace(c[:idx]), c[idx+1:]})
+	}
+	f.optionMeta["obligatory_if"] = conditions
+	return nil
+}
+
+func env(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Env option needs a value")
+	}
+	f.optionMeta["env"] = value
+	return nil
+}
+
+func positional(f *Flag, option, value string) error {
+	f.optionMeta["positional"] = true
+	return nil
+}
+
+func metavar(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Metavar option needs a value")
+	}
+	f.optionMeta["metavar"] = value
+	return nil
+}
+
+func example(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Example option needs a value")
+	}
+	f.optionMeta["example"] = strings.Replace(value, `\`, ``, -1)
+	return nil
+}
+
+func deprecated(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Deprecated option needs a value")
+	}
+	f.optionMeta["deprecated"] = strings.Replace(value, `\`, ``, -1)
+	return nil
+}
+
+func synopsis(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Synopsis option needs a value")
+	}
+	f.optionMeta["synopsis"] = value
+	return nil
+}
+
+func prompt(f *Flag, option, value string) error {
+	f.optionMeta["prompt"] = value
+	return nil
+}
+
+func override(f *Flag, option, value string) error {
+	f.optionMeta["override"] = true
+	return nil
+}
+
+func accumulate(f *Flag, option, value string) error {
+	if f.value.Type() != reflect.TypeOf(int(0)) {
+		return fmt.Errorf("Only int flags can accumulate, use a slice for repeatable flags of type %s", f.value.Type())
+	}
+	f.optionMeta["reduce"] = reducerMap["sum"]
+	return nil
+}
+
+// A reducer combines the current value of a flag given multiple times with
+// the value of its next occurrence.
+type reducer func(current, next int64) int64
+
+var (
+	reducerMap = map[string]reducer{
+		"sum": func(current, next int64) int64 { return current + next },
+		"min": func(current, next int64) int64 {
+			if next < current {
+				return next
+			}
+			return current
+		},
+		"max": func(current, next int64) int64 {
+			if next > current {
+				return next
+			}
+			return current
+		},
+		"last": func(current, next int64) int64 { return next },
+	}
+)
+
+func reduce(f *Flag, option, value string) error {
+	if f.value.Type() != reflect.TypeOf(int(0)) {
+		return fmt.Errorf("Only int flags can be reduced, use a slice for repeatable flags of type %s", f.value.Type())
+	}
+	fn, ok := reducerMap[value]
+	if !ok {
+		return fmt.Errorf("Unknown reducer %s, must be one of: sum, min, max, last", value)
+	}
+	f.optionMeta["reduce"] = fn
+	return nil
+}
+
+func replaceOnSet(f *Flag, option, value string) error {
+	if f.value.Kind() != reflect.Slice {
+		return fmt.Errorf("Only slices can be replaced on set")
+	}
+	f.optionMeta["replace_on_set"] = true
+	return nil
+}
+
+func delim(f *Flag, option, value string) error {
+	if f.value.Kind() != reflect.Slice && f.value.Kind() != reflect.Map {
+		return fmt.Errorf("Only slices and maps can be delimited")
+	}
+	if len(value) <= 0 {
+		return fmt.Errorf("Delim option needs a value")
+	}
+	f.optionMeta["delim"] = value
+	return nil
+}
+
 func mutexgroup(f *Flag, option, value string) error {
 	if len(value) <= 0 {
 		return fmt.Errorf("Mutexgroup option needs a value")
@@ -64,6 +302,129 @@ func mutexgroup(f *Flag, option, value string) error {
 	return nil
 }
 
+func groupAll(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Group-all option needs a value")
+	}
+	groups, _ := f.optionMeta["group_all"].([]string)
+	for _, group := range strings.Split(value, ",") {
+		groups = append(groups, group)
+	}
+	f.optionMeta["group_all"] = groups
+	return nil
+}
+
+func defaultValue(f *Flag, option, value string) error {
+	f.optionMeta["default"] = strings.Replace(value, `\`, ``, -1)
+	return nil
+}
+
+func sliceLimit(f *Flag, option, value string) error {
+	if f.value.Kind() != reflect.Slice {
+		return fmt.Errorf("Only slices can be limited")
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	if limit < 0 {
+		return fmt.Errorf("Limit must not be negative")
+	}
+	f.optionMeta[option] = limit
+	return nil
+}
+
+func choices(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Choices option needs a value")
+	}
+	f.optionMeta["choices"] = strings.Split(value, ",")
+	return nil
+}
+
+func validate(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Validate option needs a value")
+	}
+	validators, _ := f.optionMeta["validators"].([]func(reflect.Value) error)
+	for _, name := range strings.Split(value, ",") {
+		fn, ok := validatorMap[name]
+		if !ok {
+			return fmt.Errorf("Unknown validator %s", name)
+		}
+		validators = append(validators, fn)
+	}
+	f.optionMeta["validators"] = validators
+	return nil
+}
+
+func choicesFunc(f *Flag, option, value string) error {
+	fn, ok := choicesMap[value]
+	if !ok {
+		return fmt.Errorf("Unknown choices provider %s", value)
+	}
+	f.optionMeta["choices_func"] = fn
+	return nil
+}
+
+func choicesCaseInsensitive(f *Flag, option, value string) error {
+	f.optionMeta["choices_ci"] = true
+	return nil
+}
+
+func negatable(f *Flag, option, value string) error {
+	addNegatePrefix(f, "no-")
+	return nil
+}
+
+func negatePrefix(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Negate-prefix option needs a value")
+	}
+	addNegatePrefix(f, "no-")
+	addNegatePrefix(f, value)
+	return nil
+}
+
+func offSuffix(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		value = "-"
+	}
+	f.optionMeta["off_suffix"] = value
+	return nil
+}
+
+func addNegatePrefix(f *Flag, prefix string) {
+	prefixes, _ := f.optionMeta["negate_prefixes"].([]string)
+	for _, p := range prefixes {
+		if p == prefix {
+			return
+		}
+	}
+	f.optionMeta["negate_prefixes"] = append(prefixes, prefix)
+}
+
+func allowNonfinite(f *Flag, option, value string) error {
+	f.optionMeta["allow_nonfinite"] = true
+	return nil
+}
+
+func duration_unit(f *Flag, option, value string) error {
+	if _, err := time.ParseDuration("1" + value); err != nil {
+		return fmt.Errorf("Invalid unit %s", value)
+	}
+	f.optionMeta["duration_unit"] = value
+	return nil
+}
+
+func timeLayout(f *Flag, option, value string) error {
+	if len(value) <= 0 {
+		return fmt.Errorf("Layout option needs a value")
+	}
+	f.optionMeta["time_layouts"] = strings.Split(value, "|")
+	return nil
+}
+
 func file_create(f *Flag, option, value string) error {
 	f.optionMeta["file_mode"] = f.optionMeta["file_mode"].(int) | os.O_CREATE
 	return nil
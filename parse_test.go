@@ -1,8 +1,17 @@
 package goptions
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
 	"os"
+	"os/exec"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParse_StringValue(t *testing.T) {
@@ -357,3 +366,2243 @@ func TestParse_File(t *testing.T) {
 	options.Output.Close()
 	os.Remove("testfile")
 }
+
+func TestFlagSet_Close(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Output *os.File `goptions:"-o, create, trunc, wronly"`
+		Verbs
+		Copy struct {
+			Input *os.File `goptions:"-i, rdonly"`
+		} `goptions:"copy"`
+	}
+	defer os.Remove("testfile")
+
+	fs = NewFlagSet("goptions", &options)
+	if err = fs.Close(); err != nil {
+		t.Fatalf("Closing without files failed: %s", err)
+	}
+
+	args = []string{"-o", "testfile", "copy", "-i", "testfile"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if err = fs.Close(); err != nil {
+		t.Fatalf("Closing failed: %s", err)
+	}
+	for _, f := range []*os.File{options.Output, options.Copy.Input} {
+		if _, err := f.Stat(); err == nil {
+			t.Fatalf("File %s has not been closed", f.Name())
+		}
+	}
+	if err = fs.Close(); err != nil {
+		t.Fatalf("Closing twice failed: %s", err)
+	}
+}
+
+func TestParse_BoolDefaultTrue(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Color bool `goptions:"--color, negatable, default='true', description='Colorize output'"`
+	}
+
+	fs = NewFlagSet("goptions", &options)
+	if !options.Color {
+		t.Fatalf("Expected default value true before parsing")
+	}
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	if !strings.Contains(buf.String(), "Colorize output (default: true)") {
+		t.Fatalf("Default value not rendered in help: %q", buf.String())
+	}
+
+	args = []string{"--no-color"}
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Color {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_ArrayLimits(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Includes []string `goptions:"-i, --include, min='2', max='3'"`
+	}
+
+	args = []string{"-i", "a", "-i", "b", "-i", "c", "-i", "d"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || !strings.Contains(err.Error(), "--include") {
+		t.Fatalf("Expected error naming --include, got: %v", err)
+	}
+
+	options.Includes = nil
+	args = []string{"-i", "a"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || !strings.Contains(err.Error(), "--include") {
+		t.Fatalf("Expected error naming --include, got: %v", err)
+	}
+
+	options.Includes = nil
+	args = []string{"-i", "a", "-i", "b", "-i", "c"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if len(options.Includes) != 3 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_SliceMinimum(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Hosts []string `goptions:"--host, min='2'"`
+	}
+
+	args = []string{"--host", "a"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "flag --host requires at least 2 values (got 1)" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	options.Hosts = nil
+	args = []string{"--host", "a", "--host", "b"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+
+	options.Hosts = nil
+	args = []string{"--host", "a", "--host", "b", "--host", "c"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !reflect.DeepEqual(options.Hosts, []string{"a", "b", "c"}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_ValidateOnly(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Output *os.File `goptions:"-o, create, trunc, wronly"`
+	}
+
+	args = []string{"-o", "testfile"}
+	fs = NewFlagSet("goptions", &options)
+	fs.ValidateOnly = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Output != nil {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+	if _, err := os.Stat("testfile"); !os.IsNotExist(err) {
+		os.Remove("testfile")
+		t.Fatalf("File has been created in validate mode")
+	}
+	if len(fs.SkippedActions) != 1 {
+		t.Fatalf("Unexpected skipped actions: %#v", fs.SkippedActions)
+	}
+}
+
+func TestParse_Choices(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Level string `goptions:"--level, choices='debug,info,warn', choices-ci"`
+	}
+
+	args = []string{"--level", "INFO"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Level != "info" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--level", "Verbose"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || !strings.Contains(err.Error(), "debug, info, warn") {
+		t.Fatalf("Expected error listing the choices, got: %v", err)
+	}
+}
+
+func TestParse_RemainderString(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Fast bool `goptions:"-f"`
+
+		Verbs
+		Exec struct {
+			Quiet bool `goptions:"-q"`
+			RemainderString
+		} `goptions:"exec"`
+	}
+
+	args = []string{"-f", "exec", "-q", "--", "echo", "-n", "hello", "world"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(options.Fast &&
+		options.Exec.Quiet &&
+		options.Exec.RemainderString == "echo -n hello world") {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_VerbAlias(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbs
+		Create struct {
+			Name string `goptions:"--name, -n"`
+		} `goptions:"create"`
+		Remove struct {
+			Force bool `goptions:"--force, -f"`
+		} `goptions:"remove, rm, del"`
+	}
+
+	args = []string{"rm", "-f"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(options.Remove.Force &&
+		options.Verbs == "remove") {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	if !strings.Contains(buf.String(), "remove (rm, del):") {
+		t.Fatalf("Aliases not rendered in help: %q", buf.String())
+	}
+}
+
+func TestNewFlagSet_VerbAliasCollision(t *testing.T) {
+	defer func() {
+		err := recover()
+		if err == nil || !strings.Contains(fmt.Sprint(err), "Invalid struct field: Verb rm already exists") {
+			t.Fatalf("Unexpected panic: %v", err)
+		}
+	}()
+	var options struct {
+		Verbs
+		Remove struct{} `goptions:"remove, rm"`
+		Rm     struct{} `goptions:"rm"`
+	}
+	NewFlagSet("goptions", &options)
+}
+
+func TestFlagSet_FlagFor(t *testing.T) {
+	var options struct {
+		Name  string `goptions:"--name, -n"`
+		Fast  bool   `goptions:"-f"`
+		Color bool   `goptions:"--color, negatable"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	for _, arg := range []string{"-n", "--name", "-fn", "--no-color"} {
+		if _, ok := fs.FlagFor(arg); !ok {
+			t.Fatalf("No flag found for %s", arg)
+		}
+	}
+	if f, _ := fs.FlagFor("--name"); f.Long != "name" {
+		t.Fatalf("Unexpected flag for --name: %#v", f)
+	}
+	for _, arg := range []string{"-k", "--unknown", "name", "-", "--"} {
+		if f, ok := fs.FlagFor(arg); ok {
+			t.Fatalf("Unexpected flag for %s: %#v", arg, f)
+		}
+	}
+}
+
+func TestParse_ErrorMessage(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		APIKey string `goptions:"--api-key, obligatory, error='Please provide an API key with --api-key'"`
+	}
+	expected := "Please provide an API key with --api-key"
+
+	args = []string{}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected %q, got: %v", expected, err)
+	}
+
+	args = []string{"--api-key"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected %q, got: %v", expected, err)
+	}
+}
+
+func TestParse_Duration(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Timeout time.Duration `goptions:"-t, --timeout, unit='s'"`
+	}
+
+	args = []string{"--timeout", "30"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Timeout != 30*time.Second {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--timeout", "1m"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Timeout != time.Minute {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--timeout", "1x"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestParse_BigNumbers(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		N *big.Int   `goptions:"-n"`
+		X *big.Float `goptions:"-x"`
+	}
+
+	args = []string{"-n", "123456789012345678901234567890", "-x", "1.25e3"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	expected, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if options.N.Cmp(expected) != 0 {
+		t.Fatalf("Unexpected value: %s", options.N)
+	}
+	if options.X.Cmp(new(big.Float).SetFloat64(1250)) != 0 {
+		t.Fatalf("Unexpected value: %s", options.X)
+	}
+
+	args = []string{"-n", "12abc"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || !strings.Contains(err.Error(), "-n") {
+		t.Fatalf("Expected error naming -n, got: %v", err)
+	}
+
+	args = []string{"-x", "-Inf"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != `Non-finite value "-Inf" for -x` {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var nonfinite struct {
+		X *big.Float `goptions:"-x, allow-nonfinite"`
+	}
+	fs = NewFlagSet("goptions", &nonfinite)
+	err = fs.Parse([]string{"-x", "+Inf"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !nonfinite.X.IsInf() {
+		t.Fatalf("Unexpected value: %s", nonfinite.X)
+	}
+}
+
+func TestFlagSet_DumpSecret(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		User     string `goptions:"-u, --user"`
+		Password string `goptions:"-p, --password, secret"`
+	}
+
+	args = []string{"-u", "alice", "-p", "hunter2"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Password != "hunter2" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	var buf bytes.Buffer
+	fs.Dump(&buf)
+	expected := "--user=alice\n--password=****\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, buf.String())
+	}
+
+	data, err := json.Marshal(fs)
+	if err != nil {
+		t.Fatalf("Marshaling failed: %s", err)
+	}
+	if string(data) != `{"password":"****","user":"alice"}` {
+		t.Fatalf("Unexpected JSON: %s", data)
+	}
+}
+
+func TestFlagSet_SecretDefault(t *testing.T) {
+	var options struct {
+		Password string `goptions:"-p, --password, secret, default='hunter2', description='Password'"`
+		Token    string `goptions:"--token, secret, description='Token'"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	if strings.Contains(buf.String(), "hunter2") || !strings.Contains(buf.String(), "Password (default: ****)") ||
+		strings.Contains(buf.String(), "Token (default") {
+		t.Fatalf("Unexpected help: %q", buf.String())
+	}
+
+	buf.Reset()
+	fs.WriteMarkdown(&buf)
+	if strings.Contains(buf.String(), "hunter2") || !strings.Contains(buf.String(), "****") {
+		t.Fatalf("Unexpected Markdown: %q", buf.String())
+	}
+}
+
+func TestParse_MultiByteShortFlag(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Accent  bool   `goptions:"-É"`
+		Verbose bool   `goptions:"-v"`
+		Name    string `goptions:"-ñ"`
+	}
+
+	args = []string{"-É", "-ñ", "name"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Accent || options.Verbose || options.Name != "name" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options.Accent, options.Name = false, ""
+	args = []string{"-vÉ"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Accent || !options.Verbose {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options.Accent, options.Verbose = false, false
+	args = []string{"-Év"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Accent || !options.Verbose {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"-Éx"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "Unknown flag -x in cluster -Éx" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_ShortEqualsNotation(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Number  int    `goptions:"-n"`
+		Verbose bool   `goptions:"-v"`
+		Name    string `goptions:"-s"`
+	}
+
+	args = []string{"-n=5", "-vs=a=b"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Number != 5 || !options.Verbose || options.Name != "a=b" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"-n="}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "Flag -n needs an argument" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_FlagClusterUnknown(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Fast    bool `goptions:"-f"`
+		Verbose bool `goptions:"-v"`
+		Remainder
+	}
+
+	args = []string{"-fvx"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "Unknown flag -x in cluster -fvx" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_FlagClusterValue(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Fast bool   `goptions:"-f"`
+		Name string `goptions:"-n"`
+	}
+
+	args = []string{"-fn", "SomeName"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(options.Fast && options.Name == "SomeName") {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"-nf", "SomeName"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestParse_Counter(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbosity Counter `goptions:"-v, --verbose"`
+		Fast      bool    `goptions:"-f"`
+	}
+
+	args = []string{"-vfvv"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(options.Verbosity == 3 && options.Fast) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options.Verbosity = 0
+	args = []string{"--verbose=5"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Verbosity != 5 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_CounterMixed(t *testing.T) {
+	var options struct {
+		Verbosity Counter `goptions:"-v, --verbose"`
+	}
+
+	tests := map[string]Counter{
+		"-vv --verbose":          3,
+		"--verbose -vv":          3,
+		"-v --verbose -v":        3,
+		"-vv --verbose=1 -v":     2,
+		"--verbose -v=4 -v":      5,
+		"-v --verbose --verbose": 3,
+	}
+	for line, expected := range tests {
+		args, _ := SplitArgs(line)
+		options.Verbosity = 0
+		fs := NewFlagSet("goptions", &options)
+		err := fs.Parse(args)
+		if err != nil {
+			t.Fatalf("Parsing %q failed: %s", line, err)
+		}
+		if options.Verbosity != expected {
+			t.Fatalf("Expected %d for %q, got %d", expected, line, options.Verbosity)
+		}
+	}
+}
+
+func TestParse_EqualsNotation(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Name  string `goptions:"--name"`
+		Force bool   `goptions:"--force"`
+	}
+
+	args = []string{"--name=Some=Name", "--force=false"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(options.Name == "Some=Name" && !options.Force) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_MutexGroupViolation(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Create bool `goptions:"-c, mutexgroup='action', obligatory"`
+		Delete bool `goptions:"-d, mutexgroup='action'"`
+	}
+
+	args = []string{}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	mgerr, ok := err.(*ErrMutexGroupViolation)
+	if !ok || !mgerr.NoneSpecified || mgerr.Name != "action" || len(mgerr.Group) != 2 {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+
+	args = []string{"-c", "-d"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	mgerr, ok = err.(*ErrMutexGroupViolation)
+	if !ok || mgerr.NoneSpecified {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	if mgerr.Error() != "Only one of -c, -d can be specified" {
+		t.Fatalf("Unexpected error message: %s", mgerr)
+	}
+}
+
+func TestParse_Greedy(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool     `goptions:"-v"`
+		Command []string `goptions:"--cmd, greedy"`
+		Line    string   `goptions:"--line"`
+	}
+
+	args = []string{"-v", "--cmd", "echo", "-n", "hi", "--line", "x"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(options.Verbose &&
+		reflect.DeepEqual(options.Command, []string{"echo", "-n", "hi", "--line", "x"}) &&
+		options.Line == "") {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	var joined struct {
+		Command string `goptions:"--cmd, greedy"`
+	}
+	args = []string{"--cmd", "echo", "-n", "hi"}
+	fs = NewFlagSet("goptions", &joined)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if joined.Command != "echo -n hi" {
+		t.Fatalf("Unexpected value: %#v", joined)
+	}
+}
+
+func TestNewFlagSet_MultipleGreedy(t *testing.T) {
+	var options struct {
+		Command []string `goptions:"--cmd, greedy"`
+		Other   []string `goptions:"--other, greedy"`
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("NewFlagSet should have panicked")
+		}
+	}()
+	NewFlagSet("goptions", &options)
+}
+
+func TestParse_Accumulate(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Add int `goptions:"--add, accumulate, allow-dash-value, default='10'"`
+	}
+
+	args = []string{"--add", "2", "--add=3", "--add", "-1"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Add != 4 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_AccumulateNonInt(t *testing.T) {
+	var options struct {
+		Name string `goptions:"--name, accumulate"`
+	}
+	defer func() {
+		err := recover()
+		if err == nil || !strings.Contains(fmt.Sprint(err), "use a slice") {
+			t.Fatalf("Unexpected panic: %v", err)
+		}
+	}()
+	NewFlagSet("goptions", &options)
+}
+
+func TestParse_Reduce(t *testing.T) {
+	var options struct {
+		Add     int `goptions:"--add, reduce='sum'"`
+		Lowest  int `goptions:"--lowest, reduce='min'"`
+		Highest int `goptions:"--highest, reduce='max'"`
+		Last    int `goptions:"--last, reduce='last'"`
+	}
+
+	args := []string{
+		"--add", "3", "--add", "4",
+		"--lowest", "5", "--lowest", "2", "--lowest", "9",
+		"--highest", "5", "--highest", "9", "--highest", "2",
+		"--last", "1", "--last", "3",
+	}
+	fs := NewFlagSet("goptions", &options)
+	err := fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Add != 7 || options.Lowest != 2 || options.Highest != 9 || options.Last != 3 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_ReduceUnknown(t *testing.T) {
+	var options struct {
+		Add int `goptions:"--add, reduce='avg'"`
+	}
+	defer func() {
+		err := recover()
+		if err == nil || !strings.Contains(fmt.Sprint(err), "Unknown reducer avg") {
+			t.Fatalf("Unexpected panic: %v", err)
+		}
+	}()
+	NewFlagSet("goptions", &options)
+}
+
+func TestParse_GroupAll(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Username string `goptions:"--username, group-all='creds'"`
+		Password string `goptions:"--password, group-all='creds'"`
+	}
+
+	args = []string{"--username", "alice"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "--username requires --password" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	options.Username = ""
+	args = []string{"--username", "alice", "--password", "secret"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+
+	options.Username, options.Password = "", ""
+	args = []string{}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !reflect.DeepEqual(fs.Constraints(), []string{"All or none of --username, --password"}) {
+		t.Fatalf("Unexpected constraints: %#v", fs.Constraints())
+	}
+}
+
+func TestParse_NegatePrefix(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Feature bool `goptions:"--feature, negate-prefix='disable-', default='true'"`
+		Other   bool `goptions:"--other, negate-prefix='disable-', default='true'"`
+	}
+
+	args = []string{"--disable-feature", "--no-other"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Feature || options.Other {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_NegativeNumbers(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Offset int     `goptions:"--offset"`
+		Rate   float64 `goptions:"--rate"`
+		Name   string  `goptions:"--name"`
+		Rest   Remainder
+	}
+
+	args = []string{"--offset", "-5", "--rate", "-0.5"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Offset != -5 || options.Rate != -0.5 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	for _, args = range [][]string{
+		{"--offset", "--", "-5"},
+		{"--name", "-5"},
+	} {
+		options.Offset, options.Rate = 0, 0
+		fs = NewFlagSet("goptions", &options)
+		fs.RejectDashValues = true
+		err = fs.Parse(args)
+		if err == nil {
+			t.Fatalf("Parsing %v should have failed", args)
+		}
+	}
+
+	args = []string{"--offset", "-5", "--rate", "-0.5"}
+	fs = NewFlagSet("goptions", &options)
+	fs.RejectDashValues = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Offset != -5 || options.Rate != -0.5 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_NegativeNumbersKnownFlag(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Offset int  `goptions:"--offset"`
+		Five   bool `goptions:"-5"`
+	}
+
+	args = []string{"--offset", "-5"}
+	fs = NewFlagSet("goptions", &options)
+	fs.RejectDashValues = true
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestParse_VerbArgs(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool `goptions:"-v, --verbose"`
+
+		Verbs
+		Remote struct {
+			Name string `goptions:"--name"`
+
+			Verbs
+			Add struct {
+				Force bool `goptions:"-f"`
+			} `goptions:"add"`
+		} `goptions:"remote"`
+	}
+
+	args = []string{"-v", "remote", "--name", "origin", "add", "-f"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	remote := fs.Verbs["remote"]
+	if !reflect.DeepEqual(remote.Args(), []string{"--name", "origin", "add", "-f"}) ||
+		!reflect.DeepEqual(remote.Verbs["add"].Args(), []string{"-f"}) ||
+		fs.Args() != nil {
+		t.Fatalf("Unexpected verb arguments: %#v, %#v", remote.Args(), remote.Verbs["add"].Args())
+	}
+}
+
+func TestParse_MarkersWaiveConstraints(t *testing.T) {
+	markers := map[string]error{
+		"--help":                     ErrHelpRequest,
+		"--help-all":                 ErrHelpAllRequest,
+		"--version":                  ErrVersionRequest,
+		"--generate-bash-completion": ErrCompletionRequest,
+	}
+	constraints := map[string]func() (interface{}, []string){
+		"obligatory": func() (interface{}, []string) {
+			return &struct {
+				Help       Help       `goptions:"--help"`
+				HelpAll    HelpAll    `goptions:"--help-all"`
+				Version    Version    `goptions:"--version"`
+				Completion Completion `goptions:"--generate-bash-completion"`
+				Name       string     `goptions:"--name, obligatory"`
+			}{}, nil
+		},
+		"mutexgroup": func() (interface{}, []string) {
+			return &struct {
+				Help       Help       `goptions:"--help"`
+				HelpAll    HelpAll    `goptions:"--help-all"`
+				Version    Version    `goptions:"--version"`
+				Completion Completion `goptions:"--generate-bash-completion"`
+				Create     bool       `goptions:"--create, mutexgroup='action', obligatory"`
+				Delete     bool       `goptions:"--delete, mutexgroup='action'"`
+			}{}, []string{"--create", "--delete"}
+		},
+		"min": func() (interface{}, []string) {
+			return &struct {
+				Help       Help       `goptions:"--help"`
+				HelpAll    HelpAll    `goptions:"--help-all"`
+				Version    Version    `goptions:"--version"`
+				Completion Completion `goptions:"--generate-bash-completion"`
+				Hosts      []string   `goptions:"--host, min='2'"`
+			}{}, []string{"--host", "a"}
+		},
+		"group-all": func() (interface{}, []string) {
+			return &struct {
+				Help       Help       `goptions:"--help"`
+				HelpAll    HelpAll    `goptions:"--help-all"`
+				Version    Version    `goptions:"--version"`
+				Completion Completion `goptions:"--generate-bash-completion"`
+				Username   string     `goptions:"--username, group-all='creds'"`
+				Password   string     `goptions:"--password, group-all='creds'"`
+			}{}, []string{"--username", "alice"}
+		},
+		"positional": func() (interface{}, []string) {
+			return &struct {
+				Help       Help       `goptions:"--help"`
+				HelpAll    HelpAll    `goptions:"--help-all"`
+				Version    Version    `goptions:"--version"`
+				Completion Completion `goptions:"--generate-bash-completion"`
+				Src        string     `goptions:"positional, obligatory"`
+			}{}, nil
+		},
+		"verb": func() (interface{}, []string) {
+			return &struct {
+				Verbs
+				Deploy struct {
+					Help       Help       `goptions:"--help"`
+					HelpAll    HelpAll    `goptions:"--help-all"`
+					Version    Version    `goptions:"--version"`
+					Completion Completion `goptions:"--generate-bash-completion"`
+					Server     string     `goptions:"--server, obligatory"`
+				} `goptions:"deploy"`
+			}{}, []string{"deploy"}
+		},
+	}
+
+	for cname, constraint := range constraints {
+		for marker, expected := range markers {
+			for _, collect := range []bool{false, true} {
+				options, args := constraint()
+				fs := NewFlagSet("goptions", options)
+				fs.CollectErrors = collect
+				err := fs.Parse(append(args, marker))
+				if err != expected {
+					t.Fatalf("%s with unsatisfied %s (collect: %v): expected %v, got %v", marker, cname, collect, expected, err)
+				}
+			}
+		}
+	}
+}
+
+func TestParse_InterpolatedDefault(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		DataDir string `goptions:"--data-dir, default='/var/lib/app'"`
+		LogDir  string `goptions:"--log-dir, default='${data-dir}/logs'"`
+		LogFile string `goptions:"--log-file, default='${log-dir}/app.log'"`
+	}
+
+	args = []string{"--data-dir", "/data"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.LogDir != "/data/logs" || options.LogFile != "/data/logs/app.log" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options.DataDir, options.LogDir, options.LogFile = "", "", ""
+	args = []string{"--log-dir", "/tmp"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.DataDir != "/var/lib/app" || options.LogDir != "/tmp" || options.LogFile != "/tmp/app.log" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_InterpolatedDefaultEscape(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Name     string `goptions:"--name, default='app'"`
+		Template string `goptions:"--template, default='$${HOME}/${name}'"`
+		Literal  string `goptions:"--literal, default='$${name}'"`
+	}
+
+	args = []string{}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Template != "${HOME}/app" || options.Literal != "${name}" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_InterpolatedDefaultCycle(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		A string `goptions:"--a, default='${b}'"`
+		B string `goptions:"--b, default='x${a}'"`
+	}
+
+	args = []string{}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "Cycle in defaults: --a -> --b -> --a" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_UnknownVerbHandler(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool `goptions:"-v"`
+
+		Verbs
+		Status struct{} `goptions:"status"`
+	}
+
+	var name string
+	var verbArgs []string
+	args = []string{"-v", "foo", "--bar", "baz"}
+	fs = NewFlagSet("goptions", &options)
+	fs.UnknownVerbHandler = func(n string, a []string) error {
+		name, verbArgs = n, a
+		return nil
+	}
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Verbose || name != "foo" || !reflect.DeepEqual(verbArgs, []string{"--bar", "baz"}) {
+		t.Fatalf("Unexpected handler call: %s %#v", name, verbArgs)
+	}
+
+	name = ""
+	args = []string{"status"}
+	fs = NewFlagSet("goptions", &options)
+	fs.UnknownVerbHandler = func(n string, a []string) error {
+		name = n
+		return nil
+	}
+	err = fs.Parse(args)
+	if err != nil || name != "" {
+		t.Fatalf("Handler called for a known verb: %v", err)
+	}
+
+	args = []string{"foo"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestParse_Interactive(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		User     string `goptions:"--user, obligatory, prompt='User name'"`
+		Password string `goptions:"--password, obligatory, secret, prompt"`
+		Host     string `goptions:"--host, obligatory, prompt='Host'"`
+	}
+
+	var out bytes.Buffer
+	args = []string{"--host", "example.com"}
+	fs = NewFlagSet("goptions", &options)
+	fs.Interactive = true
+	fs.PromptInput = strings.NewReader("alice\nsecret\n")
+	fs.PromptOutput = &out
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.User != "alice" || options.Password != "secret" || options.Host != "example.com" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+	if out.String() != "User name: --password: " {
+		t.Fatalf("Unexpected prompts: %q", out.String())
+	}
+
+	options.User, options.Password, options.Host = "", "", ""
+	fs = NewFlagSet("goptions", &options)
+	fs.PromptInput = strings.NewReader("alice\nsecret\n")
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed without Interactive")
+	}
+}
+
+func TestParse_InteractiveVerb(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		User string `goptions:"--user, obligatory, prompt='User name'"`
+		Verbs
+		Deploy struct {
+			Server string `goptions:"--server, obligatory, prompt='Server'"`
+		} `goptions:"deploy"`
+	}
+
+	var out bytes.Buffer
+	args = []string{"deploy"}
+	fs = NewFlagSet("goptions", &options)
+	fs.Interactive = true
+	fs.PromptInput = strings.NewReader("example.com\nalice\n")
+	fs.PromptOutput = &out
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Deploy.Server != "example.com" || options.User != "alice" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+	if out.String() != "Server: User name: " {
+		t.Fatalf("Unexpected prompts: %q", out.String())
+	}
+}
+
+func TestParse_DecimalComma(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Rate  float64 `goptions:"--rate"`
+		Total float64 `goptions:"--total"`
+	}
+
+	args = []string{"--rate", "3,14", "--total", "1.234,5"}
+	fs = NewFlagSet("goptions", &options)
+	fs.DecimalComma = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Rate != 3.14 || options.Total != 1234.5 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--total=-1.234.567"}
+	fs = NewFlagSet("goptions", &options)
+	fs.DecimalComma = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Total != -1234567 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	for _, value := range []string{"3.14", "1.234.5", "1234.567,8", ".123", "1,2,3", "1,234.5"} {
+		args = []string{"--rate", value}
+		fs = NewFlagSet("goptions", &options)
+		fs.DecimalComma = true
+		err = fs.Parse(args)
+		if err == nil || !strings.Contains(err.Error(), "Invalid decimal") {
+			t.Fatalf("Unexpected error for %q: %v", value, err)
+		}
+	}
+
+	options.Rate, options.Total = 0, 0
+	args = []string{"--rate", "3.14"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Rate != 3.14 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--rate", "3,14"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed without DecimalComma")
+	}
+}
+
+func TestParse_GroupAllTrio(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Host   string `goptions:"--host, group-all='endpoint'"`
+		Port   int    `goptions:"--port, group-all='endpoint'"`
+		Scheme string `goptions:"--scheme, group-all='endpoint'"`
+	}
+
+	args = []string{"--port", "443", "--scheme", "https"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "--port and --scheme require --host" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	options.Port, options.Scheme = 0, ""
+	args = []string{"--scheme", "https"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "--scheme requires --host and --port" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_GroupsScopedToVerbs(t *testing.T) {
+	var options struct {
+		File string `goptions:"--file, mutexgroup='input'"`
+		URL  string `goptions:"--url, mutexgroup='input'"`
+		Verbs
+		Import struct {
+			Stdin bool   `goptions:"--stdin, mutexgroup='input', obligatory"`
+			Path  string `goptions:"--path, mutexgroup='input'"`
+		} `goptions:"import"`
+		Login struct {
+			User     string `goptions:"--user, group-all='input'"`
+			Password string `goptions:"--password, group-all='input'"`
+		} `goptions:"login"`
+	}
+
+	tests := map[string]string{
+		"--file a import --stdin":         "",
+		"--file a --url b import --stdin": "Only one of --file, --url can be specified",
+		"import --stdin --path p":         "Only one of --stdin, --path can be specified",
+		"import":                          "One of --stdin, --path must be specified",
+		"--file a login":                  "",
+		"login --user u":                  "--user requires --password",
+	}
+	for line, expected := range tests {
+		args, _ := SplitArgs(line)
+		fs := NewFlagSet("goptions", &options)
+		err := fs.Parse(args)
+		if expected == "" && err != nil {
+			t.Fatalf("Parsing %q failed: %s", line, err)
+		}
+		if expected != "" && (err == nil || err.Error() != expected) {
+			t.Fatalf("Unexpected error for %q: %v", line, err)
+		}
+	}
+}
+
+func TestParse_OffSuffix(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool `goptions:"--verbose, off-suffix"`
+		Color   bool `goptions:"--color, off-suffix='-off', default='true'"`
+	}
+
+	args = []string{"--verbose", "--color-off"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Verbose || options.Color {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options.Verbose = true
+	args = []string{"--verbose-"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Verbose {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+type mount struct {
+	Src      string
+	Dst      string
+	ReadOnly bool `goptions:"ro"`
+}
+
+func TestParse_StructSlice(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Mounts []mount `goptions:"-m, --mount"`
+	}
+
+	args = []string{"--mount", "src=/a,dst=/b", "-m", "src=/c,dst=/d,ro=true"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !reflect.DeepEqual(options.Mounts, []mount{{"/a", "/b", false}, {"/c", "/d", true}}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options.Mounts = nil
+	args = []string{"--mount", "src=/a,target=/b"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "Unknown key target for --mount, must be one of: dst, ro, src" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	var options struct {
+		Name string `goptions:"--name, obligatory"`
+		Verbs
+		Deploy struct {
+			Server string `goptions:"--server"`
+		} `goptions:"deploy"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	fs.ErrorFormatter = func(err error) string {
+		return "goptions: " + err.Error()
+	}
+	err := fs.Parse([]string{})
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+	expected := "goptions: " + err.Error()
+	if msg := fs.FormatError(err); msg != expected {
+		t.Fatalf("Unexpected message: %q", msg)
+	}
+	if msg := fs.Verbs["deploy"].FormatError(err); msg != expected {
+		t.Fatalf("Unexpected message for verb: %q", msg)
+	}
+
+	fs.ErrorFormatter = nil
+	if msg := fs.FormatError(err); msg != err.Error() {
+		t.Fatalf("Unexpected default message: %q", msg)
+	}
+}
+
+func TestPrintError_Usage(t *testing.T) {
+	var options struct {
+		Verbose bool `goptions:"-v, --verbose"`
+		Verbs
+		Deploy struct {
+			Server string `goptions:"--server, obligatory"`
+		} `goptions:"deploy"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	err := fs.Parse([]string{"deploy"})
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+
+	var buf bytes.Buffer
+	fs.PrintError(&buf, err)
+	if buf.String() != "Error: --server must be specified\n" {
+		t.Fatalf("Unexpected output: %q", buf.String())
+	}
+
+	fs.PrintUsageOnError = true
+	buf.Reset()
+	fs.PrintError(&buf, err)
+	expected := "Error: --server must be specified\n" +
+		"Usage: goptions deploy --server SERVER\n"
+	if buf.String() != expected {
+		t.Fatalf("Unexpected output: %q", buf.String())
+	}
+}
+
+type parseAndFailOptions struct {
+	Help       Help       `goptions:"-h, --help"`
+	HelpAll    HelpAll    `goptions:"--help-all"`
+	Version    Version    `goptions:"--version"`
+	Completion Completion `goptions:"--generate-bash-completion"`
+	Name       string     `goptions:"--name, obligatory"`
+	Verbs
+	Deploy struct {
+		Server string `goptions:"--server, obligatory"`
+	} `goptions:"deploy"`
+}
+
+// runParseAndFail calls ParseAndFail() with the given arguments in a
+// subprocess running TestParseAndFail_Subprocess and returns its output
+// and exit code.
+func runParseAndFail(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestParseAndFail_Subprocess$")
+	cmd.Env = append(os.Environ(), "GOPTIONS_PARSE_AND_FAIL="+strings.Join(args, "\n"))
+	var out, errOut bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("Running subprocess failed: %s", err)
+	}
+	return out.String(), errOut.String(), code
+}
+
+func TestParseAndFail_Subprocess(t *testing.T) {
+	line, ok := os.LookupEnv("GOPTIONS_PARSE_AND_FAIL")
+	if !ok {
+		t.Skip("Only run by runParseAndFail")
+	}
+	var options parseAndFailOptions
+	fs := NewFlagSet("goptions", &options)
+	fs.PrintUsageOnError = os.Getenv("GOPTIONS_PRINT_USAGE") != ""
+	if os.Getenv("GOPTIONS_VERB_PARSE_AND_FAIL") != "" {
+		// Parse a verb on its own, e.g. when dispatching to its FlagSet
+		fs.Verbs["deploy"].ParseAndFail([]string{})
+	}
+	fs.ParseAndFail(strings.Split(line, "\n"))
+	os.Exit(2)
+}
+
+func TestParseAndFail_Markers(t *testing.T) {
+	for _, marker := range []string{"--help", "--help-all", "--version", "--generate-bash-completion"} {
+		stdout, stderr, code := runParseAndFail(t, marker)
+		if code != 0 {
+			t.Fatalf("Unexpected exit code %d for %s", code, marker)
+		}
+		if strings.Contains(stdout+stderr, "Error:") {
+			t.Fatalf("Unexpected error for %s: %q", marker, stdout+stderr)
+		}
+		switch marker {
+		case "--help", "--help-all":
+			if !strings.Contains(stderr, "Usage: goptions") {
+				t.Fatalf("Missing help for %s: %q", marker, stderr)
+			}
+		case "--version":
+			if stdout != "" || stderr != "" {
+				t.Fatalf("Unexpected output for %s: %q %q", marker, stdout, stderr)
+			}
+		case "--generate-bash-completion":
+			if !strings.Contains(stdout, "complete -F _goptions 'goptions'") {
+				t.Fatalf("Missing completion script for %s: %q", marker, stdout)
+			}
+		}
+	}
+
+	stdout, _, code := runParseAndFail(t, "--name", "x", "deploy")
+	if code != 1 || !strings.Contains(stdout, "Error: --server must be specified") {
+		t.Fatalf("Unexpected failure: %d %q", code, stdout)
+	}
+}
+
+func TestParseAndFail_PrintUsageOnErrorForVerb(t *testing.T) {
+	t.Setenv("GOPTIONS_VERB_PARSE_AND_FAIL", "1")
+	t.Setenv("GOPTIONS_PRINT_USAGE", "1")
+	stdout, stderr, code := runParseAndFail(t)
+	if code != 1 {
+		t.Fatalf("Unexpected exit code %d", code)
+	}
+	expected := "Error: --server must be specified\n" +
+		"Usage: goptions deploy --server SERVER\n"
+	if stdout != expected || stderr != "" {
+		t.Fatalf("Unexpected output: %q %q", stdout, stderr)
+	}
+}
+
+func TestRequiredFlags(t *testing.T) {
+	var options struct {
+		Name   string `goptions:"--name, obligatory"`
+		Port   int    `goptions:"--port"`
+		Create bool   `goptions:"--create, mutexgroup='action', obligatory"`
+		Delete bool   `goptions:"--delete, mutexgroup='action'"`
+		Region string `goptions:"--region, obligatory-for='deploy'"`
+		Verbs
+		Deploy struct {
+			Server string `goptions:"--server, obligatory"`
+			Force  bool   `goptions:"--force"`
+		} `goptions:"deploy"`
+	}
+	names := func(flags []*Flag) []string {
+		r := make([]string, 0, len(flags))
+		for _, f := range flags {
+			r = append(r, f.Name())
+		}
+		return r
+	}
+
+	fs := NewFlagSet("goptions", &options)
+	if r := names(fs.RequiredFlags()); !reflect.DeepEqual(r, []string{"--name"}) {
+		t.Fatalf("Unexpected required flags: %#v", r)
+	}
+
+	err := fs.Parse([]string{"--name", "a", "--create", "--region", "eu", "deploy", "--server", "b"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if r := names(fs.RequiredFlags()); !reflect.DeepEqual(r, []string{"--name", "--region", "--server"}) {
+		t.Fatalf("Unexpected required flags: %#v", r)
+	}
+}
+
+func TestParse_PassedThrough(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool `goptions:"-v, --verbose"`
+		Number  int  `goptions:"-n, --number"`
+		Remainder
+		Verbs
+		Deploy struct {
+			Server string `goptions:"--server"`
+			Remainder
+		} `goptions:"deploy"`
+	}
+
+	args = []string{"--foo", "bar", "-v", "--baz=1", "-x", "-n", "3", "deploy", "--qux", "--server", "s"}
+	fs = NewFlagSet("goptions", &options)
+	fs.PassThroughUnknown = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !reflect.DeepEqual(fs.PassedThrough(), []string{"--foo", "bar", "--baz=1", "-x", "--qux"}) {
+		t.Fatalf("Unexpected tokens: %#v", fs.PassedThrough())
+	}
+	if !options.Verbose || options.Number != 3 || options.Deploy.Server != "s" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_RequireVerb(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool `goptions:"-v, --verbose"`
+		Verbs
+		Deploy struct {
+			Server string `goptions:"--server"`
+		} `goptions:"deploy, d"`
+		Remove struct {
+			Force bool `goptions:"--force"`
+		} `goptions:"rm"`
+	}
+
+	args = []string{"-v"}
+	fs = NewFlagSet("goptions", &options)
+	fs.RequireVerb = true
+	fs.Verbs["deploy"].SetDescription("Deploy the application")
+	fs.Verbs["rm"].SetDescription("Remove the application")
+	err = fs.Parse(args)
+	if _, ok := err.(*ErrVerbRequired); !ok {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "A verb must be specified, one of:\n" +
+		"    deploy (d) Deploy the application\n" +
+		"    rm         Remove the application"
+	if err.Error() != expected {
+		t.Fatalf("Unexpected error message: %q", err.Error())
+	}
+
+	args = []string{"-v", "rm"}
+	fs = NewFlagSet("goptions", &options)
+	fs.RequireVerb = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+}
+
+func TestParse_NonFiniteFloats(t *testing.T) {
+	var options struct {
+		Ratio   float64   `goptions:"--ratio"`
+		Limit   float64   `goptions:"--limit, allow-nonfinite"`
+		Weights []float64 `goptions:"--weight"`
+		Bounds  []float64 `goptions:"--bound, allow-nonfinite"`
+	}
+
+	for _, value := range []string{"NaN", "+Inf"} {
+		fs := NewFlagSet("goptions", &options)
+		err := fs.Parse([]string{"--ratio", value})
+		expected := fmt.Sprintf("Non-finite value %q for --ratio", value)
+		if err == nil || err.Error() != expected {
+			t.Fatalf("Unexpected error for %s: %v", value, err)
+		}
+	}
+
+	fs := NewFlagSet("goptions", &options)
+	err := fs.Parse([]string{"--ratio", "0.5", "--limit", "+Inf"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Ratio != 0.5 || !math.IsInf(options.Limit, 1) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse([]string{"--weight", "1", "--weight", "NaN"})
+	if err == nil || err.Error() != `Non-finite value "NaN" for --weight` {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse([]string{"--bound", "-Inf", "--bound", "0", "--bound", "+Inf"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if len(options.Bounds) != 3 || !math.IsInf(options.Bounds[0], -1) || !math.IsInf(options.Bounds[2], 1) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_VerbChaining(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool   `goptions:"-v, --verbose"`
+		Target  string `goptions:"--target, obligatory-for='deploy'"`
+		Verbs
+		Build struct {
+			Jobs int `goptions:"--jobs, default='4'"`
+		} `goptions:"build, b"`
+		Test   struct{} `goptions:"test"`
+		Deploy struct{} `goptions:"deploy"`
+	}
+
+	args = []string{"-v", "--target", "prod", "b,test,deploy"}
+	fs = NewFlagSet("goptions", &options)
+	fs.AllowVerbChaining = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !reflect.DeepEqual(fs.SelectedVerbs(), []string{"build", "test", "deploy"}) {
+		t.Fatalf("Unexpected verbs: %#v", fs.SelectedVerbs())
+	}
+	if !options.Verbose || options.Verbs != "build,test,deploy" || options.Build.Jobs != 4 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"build,deploy"}
+	fs = NewFlagSet("goptions", &options)
+	fs.AllowVerbChaining = true
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "--target must be specified" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	args = []string{"build,test"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestParse_TimeLayouts(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Since time.Time `goptions:"--since, layout='2006-01-02|2006-01-02T15:04'"`
+		Until time.Time `goptions:"--until"`
+	}
+
+	args = []string{"--since", "2023-01-31", "--until", "2023-02-01T10:00:00Z"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Since.Equal(time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)) ||
+		!options.Until.Equal(time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--since", "2023-01-31T15:04"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Since.Equal(time.Date(2023, 1, 31, 15, 4, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--since", "31.01.2023"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	expected := `Invalid time "31.01.2023" for --since, must match one of: 2006-01-02, 2006-01-02T15:04`
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_Delim(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Paths []string `goptions:"--path, delim=','"`
+		Ports []int    `goptions:"--port, delim=':'"`
+	}
+
+	args = []string{"--path", `a\,b,c`, "--path", `d\e`, "--port", "80:443"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !reflect.DeepEqual(options.Paths, []string{"a,b", "c", `d\e`}) ||
+		!reflect.DeepEqual(options.Ports, []int{80, 443}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_MapDelim(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Labels map[string]string `goptions:"--label, delim=';'"`
+		Limits map[string]int    `goptions:"--limit"`
+	}
+
+	args = []string{"--label", "env=prod;team=core", "--label", "tier=web", "--limit", "cpu=2", "--limit", "mem=512"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !reflect.DeepEqual(options.Labels, map[string]string{"env": "prod", "team": "core", "tier": "web"}) ||
+		!reflect.DeepEqual(options.Limits, map[string]int{"cpu": 2, "mem": 512}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--label", "env=prod;team"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != `Invalid entry "team" for --label, must be key=value` {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_ObligatoryIf(t *testing.T) {
+	type tlsOptions struct {
+		TLSMode string `goptions:"--tls-mode, choices='none,verify,insecure'"`
+		Strict  bool   `goptions:"--strict"`
+		CAFile  string `goptions:"--ca-file, obligatory-if='tls-mode=verify,strict=true'"`
+	}
+
+	tests := map[string]string{
+		"--tls-mode none":                "",
+		"--tls-mode insecure":            "",
+		"--tls-mode verify --ca-file ca": "",
+		"--tls-mode verify":              "--ca-file must be specified if --tls-mode is verify",
+		"--strict":                       "--ca-file must be specified if --strict is true",
+	}
+	for line, expected := range tests {
+		args, _ := SplitArgs(line)
+		var options tlsOptions
+		fs := NewFlagSet("goptions", &options)
+		err := fs.Parse(args)
+		if expected == "" && err != nil {
+			t.Fatalf("Parsing %q failed: %s", line, err)
+		}
+		if expected != "" && (err == nil || err.Error() != expected) {
+			t.Fatalf("Unexpected error for %q: %v", line, err)
+		}
+	}
+}
+
+func TestParse_ObligatoryIfPositional(t *testing.T) {
+	var options struct {
+		Deploy bool   `goptions:"--deploy"`
+		Target string `goptions:"positional, obligatory-if='deploy=true'"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	err := fs.Parse([]string{"--deploy"})
+	if err == nil || !strings.Contains(err.Error(), "must be specified if --deploy is true") {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	options.Deploy = false
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse([]string{"--deploy", "prod"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Target != "prod" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_BoolTakesValue(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	type boolOptions struct {
+		Force bool     `goptions:"-f, --force"`
+		Color bool     `goptions:"--color, negatable, default='true'"`
+		Paths []string `goptions:"positional"`
+	}
+	var options boolOptions
+
+	args = []string{"--force", "true", "positional"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Force || !reflect.DeepEqual(options.Paths, []string{"true", "positional"}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options = boolOptions{}
+	args = []string{"--force", "true", "positional"}
+	fs = NewFlagSet("goptions", &options)
+	fs.BoolTakesValue = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Force || !reflect.DeepEqual(options.Paths, []string{"positional"}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options = boolOptions{}
+	args = []string{"-f", "false", "--color", "false", "--no-color", "false"}
+	fs = NewFlagSet("goptions", &options)
+	fs.BoolTakesValue = true
+	fs.LastWins = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Force || options.Color || !reflect.DeepEqual(options.Paths, []string{"false"}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_MultipleObligatory(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Name  string `goptions:"--name, obligatory"`
+		Token string `goptions:"--token, obligatory"`
+		Port  int    `goptions:"--port"`
+	}
+
+	args = []string{"--port", "80"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "Missing required flags: --name, --token" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_DashValue(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Offset int    `goptions:"--offset, allow-dash-value"`
+		Name   string `goptions:"--name"`
+	}
+
+	args = []string{"--offset", "-5"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Offset != -5 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--name", "-foo"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Name != "-foo" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--name", "-foo"}
+	fs = NewFlagSet("goptions", &options)
+	fs.RejectDashValues = true
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+
+	args = []string{"--offset", "-5"}
+	fs = NewFlagSet("goptions", &options)
+	fs.RejectDashValues = true
+	err = fs.Parse(args)
+	if err != nil || options.Offset != -5 {
+		t.Fatalf("Parsing failed: %v", err)
+	}
+
+	args = []string{"--offset", "--"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestParse_ObligatoryForVerb(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Server string `goptions:"--server, obligatory-for='deploy'"`
+
+		Verbs
+		Deploy struct {
+			Force bool `goptions:"--force"`
+		} `goptions:"deploy"`
+		Status struct {
+			Verbose bool `goptions:"--verbose"`
+		} `goptions:"status"`
+	}
+
+	args = []string{"deploy"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "--server must be specified" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	args = []string{"--server", "127.0.0.1", "deploy"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+
+	args = []string{"status"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+
+	args = []string{}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+}
+
+func TestNewFlagSet_ObligatoryWithDefault(t *testing.T) {
+	var options struct {
+		Region string `goptions:"--region, obligatory, default='eu-west-1'"`
+	}
+	defer func() {
+		err := recover()
+		if err == nil || !strings.Contains(fmt.Sprint(err), "Obligatory flag --region cannot have a default value") {
+			t.Fatalf("Unexpected panic: %v", err)
+		}
+	}()
+	NewFlagSet("goptions", &options)
+}
+
+func TestNewFlagSet_ObligatoryBool(t *testing.T) {
+	var options struct {
+		Force bool `goptions:"--force, obligatory"`
+	}
+	defer func() {
+		err := recover()
+		if err == nil || !strings.Contains(fmt.Sprint(err), "Bool flag --force cannot be obligatory") {
+			t.Fatalf("Unexpected panic: %v", err)
+		}
+	}()
+	NewFlagSet("goptions", &options)
+}
+
+func TestNewFlagSet_ObligatoryNegatableBool(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Color bool `goptions:"--color, negatable, obligatory"`
+	}
+	args = []string{"--no-color"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Color {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestParse_PassThroughUnknown(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool   `goptions:"-v"`
+		Name    string `goptions:"--name"`
+		Remainder
+	}
+
+	args = []string{"--child-only-flag", "x", "-v", "--other=y", "-z", "--name", "n", "file"}
+	fs = NewFlagSet("goptions", &options)
+	fs.PassThroughUnknown = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(options.Verbose &&
+		options.Name == "n" &&
+		reflect.DeepEqual([]string(options.Remainder), []string{"--child-only-flag", "x", "--other=y", "-z", "file"})) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options.Verbose, options.Name, options.Remainder = false, "", nil
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Verbose || len(options.Remainder) != len(args) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_CollectErrors(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Limit int    `goptions:"-l"`
+		Name  string `goptions:"--name, obligatory"`
+		Fast  bool   `goptions:"-f"`
+	}
+
+	args = []string{"-l", "abc", "-fx"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if _, ok := err.(Errors); ok || err == nil {
+		t.Fatalf("Expected a single error, got: %#v", err)
+	}
+
+	fs = NewFlagSet("goptions", &options)
+	fs.CollectErrors = true
+	err = fs.Parse(args)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("Expected 3 errors, got: %#v", err)
+	}
+	if errs[1].Error() != "Unknown flag -x in cluster -fx" ||
+		errs[2].Error() != "--name must be specified" {
+		t.Fatalf("Unexpected errors: %s", errs)
+	}
+}
+
+func TestParse_StrictValues(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Out   string `goptions:"--out, allow-dash-value"`
+		Force bool   `goptions:"--force"`
+	}
+
+	args = []string{"--out", "--force"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Out != "--force" || options.Force {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options.Out = ""
+	fs = NewFlagSet("goptions", &options)
+	fs.StrictValues = true
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "Flag --out needs an argument (got flag --force)" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	args = []string{"--out", "-x"}
+	fs = NewFlagSet("goptions", &options)
+	fs.StrictValues = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Out != "-x" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestParse_Override(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Port     int `goptions:"--port"`
+		OverPort int `goptions:"--over-port, override"`
+	}
+
+	args = []string{"--port", "1", "--port", "2"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+
+	args = []string{"--over-port", "1", "--over-port", "2"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.OverPort != 2 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--port", "1", "--port", "2"}
+	fs = NewFlagSet("goptions", &options)
+	fs.LastWins = true
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Port != 2 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
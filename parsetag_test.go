@@ -1,7 +1,9 @@
 package goptions
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -65,3 +67,64 @@ func flagequal(f1, f2 *Flag) bool {
 		f1.Obligatory == f2.Obligatory &&
 		f1.WasSpecified == f2.WasSpecified
 }
+
+func TestNewLenientFlagSet(t *testing.T) {
+	var options struct {
+		Name string `goptions:"--name, description='Some name', cli-only, other='x'"`
+	}
+
+	fs := NewLenientFlagSet("goptions", &options)
+	err := fs.Parse([]string{"--name", "foo"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Name != "foo" || fs.Flags[0].Description != "Some name" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	defer func() {
+		err := recover()
+		if err == nil || !strings.Contains(fmt.Sprint(err), "Unknown option cli-only") {
+			t.Fatalf("Unexpected panic: %v", err)
+		}
+	}()
+	NewFlagSet("goptions", &options)
+}
+
+func TestNewFlagSet_UnknownConditionFlag(t *testing.T) {
+	var options struct {
+		Mode string `goptions:"--mode"`
+		Verbs
+		Deploy struct {
+			CAFile string `goptions:"--ca-file, obligatory-if='mode=strict,tls=true'"`
+		} `goptions:"deploy"`
+	}
+
+	for _, newFlagSet := range []func(string, interface{}) *FlagSet{NewFlagSet, NewLenientFlagSet} {
+		func() {
+			defer func() {
+				err := recover()
+				if err == nil || !strings.Contains(fmt.Sprint(err), "Unknown flag tls in condition of --ca-file") {
+					t.Fatalf("Unexpected panic: %v", err)
+				}
+			}()
+			newFlagSet("goptions", &options)
+		}()
+	}
+}
+
+func TestNewFlagSet_UnknownObligatoryForVerb(t *testing.T) {
+	var options struct {
+		Target string `goptions:"--target, obligatory-for='deploy, delpoy'"`
+		Verbs
+		Deploy struct{} `goptions:"deploy"`
+	}
+
+	defer func() {
+		err := recover()
+		if err == nil || !strings.Contains(fmt.Sprint(err), "Invalid struct field: Unknown verb delpoy in obligatory-for of --target") {
+			t.Fatalf("Unexpected panic: %v", err)
+		}
+	}()
+	NewFlagSet("goptions", &options)
+}
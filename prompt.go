@@ -0,0 +1,72 @@
+package goptions
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// promptMissing asks for the values of missing obligatory flags with the
+// `prompt` option if the FlagSet is interactive.
+func (fs *FlagSet) promptMissing() error {
+	root := fs.root()
+	if !root.Interactive {
+		return nil
+	}
+	in, out := root.PromptInput, root.PromptOutput
+	tty := false
+	if in == nil {
+		if !isTerminal(os.Stdin) {
+			return nil
+		}
+		in, tty = os.Stdin, true
+	}
+	if out == nil {
+		out = os.Stderr
+	}
+	r := root.promptReader(in)
+	for _, f := range fs.allFlags() {
+		text, ok := f.optionMeta["prompt"].(string)
+		if !ok || !f.isObligatory(fs.selectedVerbs()...) || f.isSet() {
+			continue
+		}
+		if text == "" {
+			text = f.Name()
+		}
+		fmt.Fprintf(out, "%s: ", text)
+		hidden := f.Secret && tty && setEcho(false)
+		line, err := r.ReadString('\n')
+		if hidden {
+			setEcho(true)
+			fmt.Fprintln(out)
+		}
+		if err != nil && line == "" {
+			// Reported as missing by checkConstraints()
+			return nil
+		}
+		err = f.setValue(strings.TrimRight(line, "\r\n"))
+		if err != nil {
+			return err
+		}
+		f.WasSpecified = true
+		f.source = SourceCLI
+	}
+	return nil
+}
+
+// promptReader returns the buffered reader for in. The verbs and the root
+// prompt after each other, so they have to share the reader in order not
+// to lose the input buffered ahead.
+func (fs *FlagSet) promptReader(in io.Reader) *bufio.Reader {
+	if fs.prompter == nil || fs.prompterInput != in {
+		fs.prompter, fs.prompterInput = bufio.NewReader(in), in
+	}
+	return fs.prompter
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,96 @@
+package goptions
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+// JSONSchema returns a JSON Schema describing the config files accepted
+// by LoadJSON(). Every flag with a long name becomes a property with the
+// flag's type, description and choices, obligatory flags are required and
+// verbs become nested objects.
+func (fs *FlagSet) JSONSchema() ([]byte, error) {
+	schema := fs.jsonSchema()
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func (fs *FlagSet) jsonSchema() map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+	for _, f := range fs.Flags {
+		if f.Long == "" || f.isMarker() {
+			continue
+		}
+		properties[f.Long] = f.jsonSchema()
+		if f.isObligatory() {
+			required = append(required, f.Long)
+		}
+	}
+	for name, verb := range fs.Verbs {
+		properties[name] = verb.jsonSchema()
+	}
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if fs.Description != "" {
+		schema["description"] = fs.Description
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (f *Flag) jsonSchema() map[string]interface{} {
+	t := f.value.Type()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	schema := map[string]interface{}{
+		"type": jsonSchemaType(t),
+	}
+	if choices := f.Choices(); choices != nil {
+		schema["enum"] = choices
+	}
+	if f.value.Kind() == reflect.Slice {
+		schema = map[string]interface{}{
+			"type":  "array",
+			"items": schema,
+		}
+	}
+	if f.Description != "" {
+		schema["description"] = f.Description
+	}
+	return schema
+}
+
+// jsonSchemaType returns the JSON type of values of type t. Values which
+// are not booleans or numbers, like durations and Marshalers, are given as
+// strings.
+func jsonSchemaType(t reflect.Type) string {
+	marshaler := reflect.TypeOf(new(Marshaler)).Elem()
+	switch {
+	case t.Implements(marshaler) || reflect.PtrTo(t).Implements(marshaler):
+		return "string"
+	case t == reflect.TypeOf(new(time.Duration)).Elem():
+		return "string"
+	case t == reflect.TypeOf(new(*big.Int)).Elem():
+		return "integer"
+	case t == reflect.TypeOf(new(*big.Float)).Elem():
+		return "number"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "string"
+}
@@ -0,0 +1,59 @@
+package goptions
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	var options struct {
+		Server string   `goptions:"--server, obligatory, description='Server to connect to'"`
+		Mode   string   `goptions:"--mode, choices='fast,slow'"`
+		Port   int      `goptions:"-p, --port"`
+		Tags   []string `goptions:"--tag"`
+		Help   Help     `goptions:"-h, --help"`
+
+		Verbs
+		Delete struct {
+			Force bool `goptions:"--force"`
+		} `goptions:"delete"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	b, err := fs.JSONSchema()
+	if err != nil {
+		t.Fatalf("Generating schema failed: %s", err)
+	}
+	var schema map[string]interface{}
+	err = json.Unmarshal(b, &schema)
+	if err != nil {
+		t.Fatalf("Invalid schema: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []interface{}{"server"},
+		"properties": map[string]interface{}{
+			"server": map[string]interface{}{"type": "string", "description": "Server to connect to"},
+			"mode":   map[string]interface{}{"type": "string", "enum": []interface{}{"fast", "slow"}},
+			"port":   map[string]interface{}{"type": "integer"},
+			"tag": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+			"delete": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"force": map[string]interface{}{"type": "boolean"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Fatalf("Unexpected schema: %s", b)
+	}
+}
@@ -0,0 +1,67 @@
+package goptions
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A SchemeConstructor creates the value of an interface flag from the
+// value given on the command line, e.g. `s3://bucket`.
+type SchemeConstructor func(value string) (interface{}, error)
+
+var (
+	schemeMap = map[reflect.Type]map[string]SchemeConstructor{}
+)
+
+// RegisterScheme makes flags of an interface type parseable. The interface
+// is given as a pointer to it, e.g. `(*Storage)(nil)`. A value starting with
+// `scheme:` (e.g. `file:/path` or `s3://bucket`) is passed as a whole to the
+// constructor registered for the scheme and the returned value, which has
+// to implement the interface, is assigned to the flag. Values with
+// unregistered schemes are rejected by Parse().
+// RegisterScheme panics if iface is not a pointer to an interface.
+func RegisterScheme(iface interface{}, scheme string, fn SchemeConstructor) {
+	t := reflect.TypeOf(iface)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic(fmt.Sprintf("RegisterScheme needs a pointer to an interface, got %T", iface))
+	}
+	constructors, ok := schemeMap[t.Elem()]
+	if !ok {
+		constructors = map[string]SchemeConstructor{}
+		schemeMap[t.Elem()] = constructors
+	}
+	constructors[scheme] = fn
+}
+
+func schemeValueParser(t reflect.Type) (valueParser, bool) {
+	constructors, ok := schemeMap[t]
+	if !ok {
+		return nil, false
+	}
+	return func(f *Flag, val string) (reflect.Value, error) {
+		scheme := ""
+		if idx := strings.Index(val, ":"); idx > 0 {
+			scheme = val[:idx]
+		}
+		fn, ok := constructors[scheme]
+		if !ok {
+			schemes := make([]string, 0, len(constructors))
+			for name := range constructors {
+				schemes = append(schemes, name)
+			}
+			sort.Strings(schemes)
+			return reflect.Value{}, fmt.Errorf("Unknown scheme in %q for %s, must be one of: %s", val, f.Name(), strings.Join(schemes, ", "))
+		}
+		v, err := fn(val)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Invalid value for %s: %s", f.Name(), err)
+		}
+		r := reflect.ValueOf(v)
+		if !r.IsValid() || !r.Type().Implements(t) {
+			return reflect.Value{}, fmt.Errorf("Scheme %s of %s returned %T, which does not implement %s", scheme, f.Name(), v, t)
+		}
+		return r.Convert(t), nil
+	}, true
+}
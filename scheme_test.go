@@ -0,0 +1,86 @@
+package goptions
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type storage interface {
+	Location() string
+}
+
+type fileStorage struct {
+	Path string
+}
+
+func (s *fileStorage) Location() string {
+	return s.Path
+}
+
+type s3Storage struct {
+	Bucket string
+}
+
+func (s *s3Storage) Location() string {
+	return s.Bucket
+}
+
+func registerStorageSchemes() {
+	RegisterScheme((*storage)(nil), "file", func(value string) (interface{}, error) {
+		return &fileStorage{strings.TrimPrefix(value, "file:")}, nil
+	})
+	RegisterScheme((*storage)(nil), "s3", func(value string) (interface{}, error) {
+		bucket := strings.TrimPrefix(value, "s3://")
+		if bucket == "" {
+			return nil, fmt.Errorf("missing bucket")
+		}
+		return &s3Storage{bucket}, nil
+	})
+}
+
+func TestRegisterScheme(t *testing.T) {
+	registerStorageSchemes()
+	defer delete(schemeMap, reflect.TypeOf((*storage)(nil)).Elem())
+
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Backend storage   `goptions:"--backend"`
+		Mirrors []storage `goptions:"--mirror"`
+	}
+	args = []string{"--backend", "file:/var/data", "--mirror", "s3://a", "--mirror", "file:/b"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if b, ok := options.Backend.(*fileStorage); !ok || b.Path != "/var/data" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+	if len(options.Mirrors) != 2 {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+	if m, ok := options.Mirrors[0].(*s3Storage); !ok || m.Bucket != "a" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+	if m, ok := options.Mirrors[1].(*fileStorage); !ok || m.Path != "/b" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--backend", "gs://bucket"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || !strings.Contains(err.Error(), "Unknown scheme") || !strings.Contains(err.Error(), "file, s3") {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	args = []string{"--backend", "s3://"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || !strings.Contains(err.Error(), "missing bucket") {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
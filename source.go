@@ -0,0 +1,117 @@
+package goptions
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Source describes where the value of a flag came from. Sources with a
+// higher value take precedence over sources with a lower value.
+type Source int
+
+const (
+	// The flag has its zero value.
+	SourceUnset Source = iota
+	// The value has been set before parsing, either in the struct or
+	// with the `default` option.
+	SourceDefault
+	// The value has been loaded from a config file.
+	SourceConfig
+	// The value has been read from an environment variable.
+	SourceEnv
+	// The value has been given on the command line.
+	SourceCLI
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceUnset:
+		return "unset"
+	case SourceDefault:
+		return "default"
+	case SourceConfig:
+		return "config"
+	case SourceEnv:
+		return "env"
+	case SourceCLI:
+		return "command line"
+	}
+	return fmt.Sprintf("Source(%d)", int(s))
+}
+
+// Source returns where the value of the named flag came from. The name
+// can be given with or without leading dashes.
+func (fs *FlagSet) Source(name string) (Source, error) {
+	f, err := fs.lookupFlag(name)
+	if err != nil {
+		return SourceUnset, err
+	}
+	return f.source, nil
+}
+
+// lookupFlag returns the flag with the given name, which can be given
+// with or without leading dashes.
+func (fs *FlagSet) lookupFlag(name string) (*Flag, error) {
+	f := fs.FlagByName(name)
+	if f == nil {
+		f = fs.FlagByName("--" + name)
+	}
+	if f == nil {
+		return nil, fmt.Errorf("Unknown flag %s", name)
+	}
+	return f, nil
+}
+
+// setFromSource sets the value of the flag unless it has been set by a
+// source with a higher precedence already.
+func (f *Flag) setFromSource(value string, src Source) error {
+	if f.source > src {
+		return nil
+	}
+	err := f.setValue(value)
+	if err != nil {
+		return err
+	}
+	f.source = src
+	return nil
+}
+
+// isSet returns true if the flag has been given a value by the user,
+// i.e. on the command line, by an environment variable or in a config file.
+func (f *Flag) isSet() bool {
+	return f.WasSpecified || f.source >= SourceConfig
+}
+
+// applyEnv sets the flags which have not been specified on the command
+// line from their environment variables.
+func (fs *FlagSet) applyEnv() error {
+	for _, f := range fs.Flags {
+		name, ok := f.envName()
+		if !ok || f.source == SourceCLI {
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		err := f.setFromSource(value, SourceEnv)
+		if err != nil {
+			return fmt.Errorf("Invalid value of %s for %s: %s", name, f.Name(), err)
+		}
+	}
+	return nil
+}
+
+// envName returns the name of the environment variable of the flag. The
+// `env` option takes precedence over the name derived from the EnvPrefix.
+func (f *Flag) envName() (string, bool) {
+	if name, ok := f.optionMeta["env"].(string); ok {
+		return name, true
+	}
+	prefix := f.flagSet.root().EnvPrefix
+	if prefix == "" || f.Long == "" || f.isMarker() {
+		return "", false
+	}
+	return prefix + "_" + strings.ToUpper(strings.Replace(f.Long, "-", "_", -1)), true
+}
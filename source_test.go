@@ -0,0 +1,257 @@
+package goptions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	options := struct {
+		CLI     string `goptions:"--cli, env='GOPTIONS_TEST_CLI'"`
+		Env     string `goptions:"--env, env='GOPTIONS_TEST_ENV'"`
+		Config  string `goptions:"--config"`
+		Default string `goptions:"--default"`
+		Unset   string `goptions:"--unset"`
+	}{
+		CLI:     "default",
+		Env:     "default",
+		Config:  "default",
+		Default: "default",
+	}
+	t.Setenv("GOPTIONS_TEST_CLI", "env")
+	t.Setenv("GOPTIONS_TEST_ENV", "env")
+
+	fs = NewFlagSet("goptions", &options)
+	err = fs.LoadJSON(strings.NewReader(`{"cli": "config", "env": "config", "config": "config"}`))
+	if err != nil {
+		t.Fatalf("Loading config failed: %s", err)
+	}
+	args = []string{"--cli", "cli"}
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+
+	expected := map[string]Source{
+		"--cli":     SourceCLI,
+		"--env":     SourceEnv,
+		"--config":  SourceConfig,
+		"--default": SourceDefault,
+		"unset":     SourceUnset,
+	}
+	for name, src := range expected {
+		s, err := fs.Source(name)
+		if err != nil {
+			t.Fatalf("Source(%s) failed: %s", name, err)
+		}
+		if s != src {
+			t.Fatalf("Expected source %s for %s, got %s", src, name, s)
+		}
+	}
+	if !(options.CLI == "cli" &&
+		options.Env == "env" &&
+		options.Config == "config" &&
+		options.Default == "default" &&
+		options.Unset == "") {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	if _, err := fs.Source("--unknown"); err == nil {
+		t.Fatalf("Source of an unknown flag should have failed")
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		DryRun   bool   `goptions:"--dry-run"`
+		Name     string `goptions:"--name"`
+		Explicit string `goptions:"--explicit, env='GOPTIONS_TEST_EXPLICIT'"`
+	}
+
+	t.Setenv("APP_DRY_RUN", "true")
+	t.Setenv("APP_NAME", "env")
+	t.Setenv("APP_EXPLICIT", "derived")
+	t.Setenv("GOPTIONS_TEST_EXPLICIT", "explicit")
+
+	args = []string{"--name", "cli"}
+	fs = NewFlagSet("goptions", &options)
+	fs.EnvPrefix = "APP"
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.DryRun ||
+		options.Name != "cli" ||
+		options.Explicit != "explicit" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Servers []string `goptions:"--server"`
+		Limit   int      `goptions:"--limit"`
+
+		Verbs
+		Create struct {
+			Force bool `goptions:"--force"`
+		} `goptions:"create"`
+	}
+
+	fs = NewFlagSet("goptions", &options)
+	err = fs.LoadJSON(strings.NewReader(`{"server": ["a", "b"], "limit": 5, "create": {"force": true}}`))
+	if err != nil {
+		t.Fatalf("Loading config failed: %s", err)
+	}
+	if !(len(options.Servers) == 2 &&
+		options.Limit == 5 &&
+		options.Create.Force) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	fs = NewFlagSet("goptions", &options)
+	err = fs.LoadJSON(strings.NewReader(`{"unknown": 5}`))
+	if err == nil {
+		t.Fatalf("Loading config should have failed")
+	}
+}
+
+func TestLoadJSON_Values(t *testing.T) {
+	var err error
+	var fs *FlagSet
+	type configOptions struct {
+		Name   string            `goptions:"--name"`
+		Ports  []int             `goptions:"--port"`
+		Labels map[string]string `goptions:"--label"`
+	}
+	var options configOptions
+
+	fs = NewFlagSet("goptions", &options)
+	err = fs.LoadJSON(strings.NewReader(`{"port": [80, 443], "label": {"env": "prod", "tier": 2}}`))
+	if err != nil {
+		t.Fatalf("Loading config failed: %s", err)
+	}
+	if !reflect.DeepEqual(options.Ports, []int{80, 443}) ||
+		!reflect.DeepEqual(options.Labels, map[string]string{"env": "prod", "tier": "2"}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	for _, config := range []string{`{"name": null}`, `{"port": [[80]]}`, `{"label": {"env": ["a"]}}`, `{"port": [null]}`} {
+		options = configOptions{}
+		fs = NewFlagSet("goptions", &options)
+		err = fs.LoadJSON(strings.NewReader(config))
+		if err == nil || !strings.Contains(err.Error(), "Invalid value in config for --") {
+			t.Fatalf("Unexpected error for %s: %v", config, err)
+		}
+	}
+	if options.Name != "" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestLoadJSON_ReplaceOnSet(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Hosts []string `goptions:"--host, replace-on-set"`
+		Tags  []string `goptions:"--tag"`
+	}
+
+	args = []string{"--host", "c", "--host", "d", "--tag", "z"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.LoadJSON(strings.NewReader(`{"host": ["a", "b"], "tag": ["x", "y"]}`))
+	if err != nil {
+		t.Fatalf("Loading config failed: %s", err)
+	}
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !reflect.DeepEqual(options.Hosts, []string{"c", "d"}) ||
+		!reflect.DeepEqual(options.Tags, []string{"x", "y", "z"}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestSource_Obligatory(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Token  string   `goptions:"--token, obligatory, env='GOPTIONS_TEST_TOKEN'"`
+		Region string   `goptions:"--region, obligatory"`
+		Tags   []string `goptions:"--tag"`
+	}
+
+	t.Setenv("GOPTIONS_TEST_TOKEN", "secret")
+	args = []string{"--tag", "z"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.LoadJSON(strings.NewReader(`{"region": "eu", "tag": ["x", "y"]}`))
+	if err != nil {
+		t.Fatalf("Loading config failed: %s", err)
+	}
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Token != "secret" || options.Region != "eu" ||
+		!reflect.DeepEqual(options.Tags, []string{"x", "y", "z"}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Name    string `goptions:"--name, env='GOPTIONS_TEST_NAME'"`
+		Server  string `goptions:"--server, env='GOPTIONS_TEST_SERVER'"`
+		DryRun  bool   `goptions:"--dry-run"`
+		Verbose bool   `goptions:"--verbose"`
+	}
+
+	envFile := `
+# Comments and blank lines are ignored
+GOPTIONS_TEST_NAME="from file"
+GOPTIONS_TEST_SERVER=file.example.com
+export APP_DRY_RUN=true
+OTHER=value
+`
+	args = []string{"--server", "cli.example.com"}
+	fs = NewFlagSet("goptions", &options)
+	fs.EnvPrefix = "APP"
+	err = fs.LoadEnvFile(strings.NewReader(envFile))
+	if err != nil {
+		t.Fatalf("Loading env file failed: %s", err)
+	}
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Name != "from file" ||
+		options.Server != "cli.example.com" ||
+		!options.DryRun ||
+		options.Verbose {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+	if src, _ := fs.Source("name"); src != SourceEnv {
+		t.Fatalf("Unexpected source: %s", src)
+	}
+
+	fs = NewFlagSet("goptions", &options)
+	err = fs.LoadEnvFile(strings.NewReader("NOT A VALID LINE\n"))
+	if err == nil {
+		t.Fatalf("Loading an invalid env file should have failed")
+	}
+}
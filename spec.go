@@ -0,0 +1,129 @@
+package goptions
+
+import (
+	"reflect"
+)
+
+// A Spec holds the flags of an options struct type with their parsed tags.
+// Creating FlagSets from a Spec skips the reflection and tag parsing done by
+// NewFlagSet(), which makes it cheap to parse arguments into many
+// instances of the same struct type.
+type Spec struct {
+	typ   reflect.Type
+	proto *FlagSet
+}
+
+// NewSpec parses the tags of the struct type v points to, like
+// NewFlagSet(). v is only used for its type and is not modified.
+func NewSpec(name string, v interface{}) *Spec {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic("Value type is not a pointer to a struct")
+	}
+	return &Spec{
+		typ:   t,
+		proto: newFlagset(name, reflect.New(t.Elem()).Elem(), nil, false),
+	}
+}
+
+// NewFlagSet returns a FlagSet for v, which has to be a pointer to the
+// Spec's struct type. The defaults are applied to v as by NewFlagSet().
+func (s *Spec) NewFlagSet(v interface{}) *FlagSet {
+	if reflect.TypeOf(v) != s.typ {
+		panic("Value type does not match the type of the Spec")
+	}
+	return s.proto.clone(reflect.ValueOf(v).Elem(), nil, make(map[*Flag]*Flag))
+}
+
+// ParseInto parses args into v, which has to be a pointer to the Spec's
+// struct type.
+func (s *Spec) ParseInto(v interface{}, args []string) error {
+	return s.NewFlagSet(v).Parse(args)
+}
+
+// Clone returns a copy of the FlagSet bound to a new instance of its struct
+// type, which is returned as a pointer. The new struct only holds the
+// values of the `default` options, and parsing into the copy does not touch
+// the FlagSet or its struct, so copies can be used concurrently.
+// Flags and verbs which are not fields of the struct (e.g. added with
+// AddFlag() or Merge()) keep their values, which are shared by all copies.
+func (fs *FlagSet) Clone() (*FlagSet, interface{}) {
+	v := reflect.New(fs.structValue.Type())
+	return fs.clone(v.Elem(), nil, make(map[*Flag]*Flag)), v.Interface()
+}
+
+// clone returns a copy of the FlagSet whose flags are bound to the fields
+// of structValue. flags maps the flags of the FlagSet to their copies, so
+// flags shared with the verbs are only copied once.
+func (fs *FlagSet) clone(structValue reflect.Value, parent *FlagSet, flags map[*Flag]*Flag) *FlagSet {
+	r := *fs
+	r.parent = parent
+	r.structValue = structValue
+	r.selectedVerb = nil
+	r.chainedVerbs = nil
+	r.args = nil
+	r.passedThrough = nil
+	r.closers = nil
+	r.warnings = nil
+	r.prompter, r.prompterInput = nil, nil
+	r.SkippedActions = nil
+
+	cloneFlag := func(f *Flag) *Flag {
+		if f == nil {
+			return nil
+		}
+		if c, ok := flags[f]; ok {
+			return c
+		}
+		c := *f
+		if isField(f.value, fs.structValue, f.index) {
+			c.value = structValue.Field(f.index)
+		}
+		c.flagSet = &r
+		c.WasSpecified = false
+		c.optionMeta = make(map[string]interface{}, len(f.optionMeta))
+		for k, v := range f.optionMeta {
+			c.optionMeta[k] = v
+		}
+		err := c.initValue()
+		if err != nil {
+			panic(err)
+		}
+		flags[f] = &c
+		return &c
+	}
+	r.Flags = make([]*Flag, len(fs.Flags))
+	for i, f := range fs.Flags {
+		r.Flags[i] = cloneFlag(f)
+	}
+	r.positionals = nil
+	for _, f := range fs.positionals {
+		r.positionals = append(r.positionals, cloneFlag(f))
+	}
+	r.remainderFlag = cloneFlag(fs.remainderFlag)
+	r.greedyFlag = cloneFlag(fs.greedyFlag)
+	r.verbFlag = cloneFlag(fs.verbFlag)
+
+	if fs.Verbs != nil {
+		r.Verbs = make(map[string]*FlagSet, len(fs.Verbs))
+		for name, verb := range fs.Verbs {
+			verbValue := verb.structValue
+			if isField(verb.structValue, fs.structValue, verb.index) {
+				verbValue = structValue.Field(verb.index)
+			}
+			r.Verbs[name] = verb.clone(verbValue, &r, flags)
+		}
+	}
+	r.createMaps()
+	return &r
+}
+
+// isField returns true if v is the field with the given index of
+// structValue rather than a value bound by AddFlag() or Merge().
+func isField(v, structValue reflect.Value, index int) bool {
+	if !v.CanAddr() || !structValue.IsValid() || index >= structValue.NumField() {
+		return false
+	}
+	field := structValue.Field(index)
+	return field.Type() == v.Type() && field.UnsafeAddr() == v.UnsafeAddr()
+}
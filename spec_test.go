@@ -0,0 +1,140 @@
+package goptions
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type specOptions struct {
+	Verbose Counter  `goptions:"-v, --verbose"`
+	Name    string   `goptions:"-n, --name, default='unnamed'"`
+	Mode    string   `goptions:"--mode, choices='fast,slow', default='fast'"`
+	Hosts   []string `goptions:"--host"`
+	Src     string   `goptions:"positional"`
+	Rest    Remainder
+
+	Verbs
+	Deploy struct {
+		Server string `goptions:"--server, obligatory"`
+		Force  bool   `goptions:"-f, --force"`
+	} `goptions:"deploy"`
+}
+
+func TestSpec(t *testing.T) {
+	spec := NewSpec("goptions", (*specOptions)(nil))
+	for _, args := range [][]string{
+		{},
+		{"-vv", "--host", "a", "--host", "b", "src"},
+		{"--name", "other", "--mode", "slow", "deploy", "--server", "srv", "-f", "--", "x", "y"},
+		{"--mode", "medium"},
+		{"deploy"},
+	} {
+		var expected, actual specOptions
+		errExpected := NewFlagSet("goptions", &expected).Parse(args)
+		errActual := spec.ParseInto(&actual, args)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("Unexpected value for %v: %#v, expected %#v", args, actual, expected)
+		}
+		if (errExpected == nil) != (errActual == nil) ||
+			(errExpected != nil && errExpected.Error() != errActual.Error()) {
+			t.Fatalf("Unexpected error for %v: %v, expected %v", args, errActual, errExpected)
+		}
+	}
+}
+
+func TestFlagSet_Clone(t *testing.T) {
+	var options specOptions
+	fs := NewFlagSet("goptions", &options)
+
+	argsList := [][]string{
+		{"-vv", "--host", "a", "src"},
+		{"--name", "other", "deploy", "--server", "srv"},
+	}
+	results := make([]*specOptions, len(argsList))
+	errs := make([]error, len(argsList))
+	var wg sync.WaitGroup
+	for i, args := range argsList {
+		clone, v := fs.Clone()
+		results[i] = v.(*specOptions)
+		wg.Add(1)
+		go func(i int, args []string) {
+			defer wg.Done()
+			errs[i] = clone.Parse(args)
+		}(i, args)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Parsing %v failed: %s", argsList[i], err)
+		}
+	}
+	if r := results[0]; r.Verbose != 2 || r.Name != "unnamed" || !reflect.DeepEqual(r.Hosts, []string{"a"}) || r.Src != "src" {
+		t.Fatalf("Unexpected value: %#v", r)
+	}
+	if r := results[1]; r.Verbose != 0 || r.Name != "other" || r.Verbs != "deploy" || r.Deploy.Server != "srv" {
+		t.Fatalf("Unexpected value: %#v", r)
+	}
+	if !reflect.DeepEqual(options, specOptions{Name: "unnamed", Mode: "fast"}) {
+		t.Fatalf("Unexpected value of the original: %#v", options)
+	}
+}
+
+func TestFlagSet_CloneAddedFlag(t *testing.T) {
+	var err error
+	var options struct {
+		Name string `goptions:"--name"`
+		Mode string `goptions:"--mode"`
+	}
+	fs := NewFlagSet("goptions", &options)
+	count := fs.Int("--count")
+	var other struct {
+		Level string `goptions:"--level"`
+	}
+	err = fs.Merge(NewFlagSet("other", &other))
+	if err != nil {
+		t.Fatalf("Merging failed: %s", err)
+	}
+
+	clone, v := fs.Clone()
+	err = clone.Parse([]string{"--count", "5", "--level", "high", "--mode", "fast"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if *count != 5 || other.Level != "high" {
+		t.Fatalf("Unexpected values: %d, %#v", *count, other)
+	}
+	if r := v.(*struct {
+		Name string `goptions:"--name"`
+		Mode string `goptions:"--mode"`
+	}); r.Name != "" || r.Mode != "fast" {
+		t.Fatalf("Unexpected value: %#v", r)
+	}
+	if options.Name != "" || options.Mode != "" {
+		t.Fatalf("Unexpected value of the original: %#v", options)
+	}
+}
+
+func BenchmarkNewFlagSet(b *testing.B) {
+	args := []string{"-vv", "--host", "a", "deploy", "--server", "srv"}
+	for i := 0; i < b.N; i++ {
+		var options specOptions
+		err := NewFlagSet("goptions", &options).Parse(args)
+		if err != nil {
+			b.Fatalf("Parsing failed: %s", err)
+		}
+	}
+}
+
+func BenchmarkSpec_ParseInto(b *testing.B) {
+	args := []string{"-vv", "--host", "a", "deploy", "--server", "srv"}
+	spec := NewSpec("goptions", (*specOptions)(nil))
+	for i := 0; i < b.N; i++ {
+		var options specOptions
+		err := spec.ParseInto(&options, args)
+		if err != nil {
+			b.Fatalf("Parsing failed: %s", err)
+		}
+	}
+}
@@ -4,6 +4,25 @@ package goptions
 // Parse() to return ErrHelpRequest.
 type Help bool
 
+// HelpAll defines a flag requesting the help of the program including all
+// verbs. Like Help, it causes Parse() to return ErrHelpAllRequest.
+type HelpAll bool
+
+// Version defines a flag requesting the version of the program. Like Help,
+// it causes Parse() to return ErrVersionRequest.
+type Version bool
+
+// Completion defines a flag requesting a shell completion script (e.g.
+// `--generate-bash-completion`). Like Help, it causes Parse() to return
+// ErrCompletionRequest. The script can be written with
+// FlagSet.WriteBashCompletion().
+type Completion bool
+
+// A Counter counts how often a flag has been specified (e.g. `-vvv`). It
+// does not take a separate value, but the value can be set explicitly with
+// the equals notation (e.g. `--verbose=5`).
+type Counter int
+
 // Verbs marks the point in the struct where the verbs start. Its value will be
 // the name of the selected verb.
 type Verbs string
@@ -12,3 +31,9 @@ type Verbs string
 // the containing options struct have a remainder field, only the latter one
 // will be used.
 type Remainder []string
+
+// A RemainderString catches all excessive arguments like a Remainder, but
+// joins them with a single space. The quoting of the original arguments is
+// lost, i.e. `-- echo "hello world"` and `-- echo hello world` yield the
+// same value. Use a Remainder if the arguments need to be preserved.
+type RemainderString string
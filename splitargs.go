@@ -0,0 +1,91 @@
+package goptions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"unicode"
+)
+
+// SplitArgs splits a command line into arguments which can be passed to
+// FlagSet.Parse(). Arguments are separated by whitespace. Like in a shell,
+// single quotes preserve every character up to the next single quote,
+// double quotes preserve every character except for `\"` and `\\`, and a
+// backslash outside of quotes escapes the following character.
+func SplitArgs(line string) ([]string, error) {
+	args := make([]string, 0)
+	var arg []rune
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, c := range line {
+		switch {
+		case escaped:
+			if quote == '"' && c != '"' && c != '\\' {
+				arg = append(arg, '\\')
+			}
+			arg = append(arg, c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				arg = append(arg, c)
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else if c == '\\' {
+				escaped = true
+			} else {
+				arg = append(arg, c)
+			}
+		case c == '\\':
+			escaped = true
+			inArg = true
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case unicode.IsSpace(c):
+			if inArg {
+				args = append(args, string(arg))
+				arg = arg[0:0]
+				inArg = false
+			}
+		default:
+			arg = append(arg, c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("Unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("Unterminated escape sequence")
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	return args, nil
+}
+
+// argsFromStdin reads the arguments from the ArgsInput of the FlagSet or
+// from stdin if it is not a terminal.
+func (fs *FlagSet) argsFromStdin() ([]string, error) {
+	in := fs.ArgsInput
+	if in == nil {
+		if isTerminal(os.Stdin) {
+			return nil, nil
+		}
+		in = os.Stdin
+	}
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read arguments: %s", err)
+	}
+	args, err := SplitArgs(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid arguments: %s", err)
+	}
+	return args, nil
+}
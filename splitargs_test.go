@@ -0,0 +1,98 @@
+package goptions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := map[string][]string{
+		``:                          []string{},
+		`--name foo`:                []string{"--name", "foo"},
+		`  --name   foo  `:          []string{"--name", "foo"},
+		`--name 'John Doe'`:         []string{"--name", "John Doe"},
+		`--name "John \"J\" Doe"`:   []string{"--name", `John "J" Doe`},
+		`--path "C:\dir"`:           []string{"--path", `C:\dir`},
+		`--name John\ Doe`:          []string{"--name", "John Doe"},
+		`--empty '' ""`:             []string{"--empty", "", ""},
+		`--mixed ab'c d'"e f"\ g`:   []string{"--mixed", "abc de f g"},
+		"--tabs\tare\nwhitespace\t": []string{"--tabs", "are", "whitespace"},
+	}
+	for line, expected := range tests {
+		args, err := SplitArgs(line)
+		if err != nil {
+			t.Fatalf("Splitting %q failed: %s", line, err)
+		}
+		if !reflect.DeepEqual(args, expected) {
+			t.Fatalf("Expected %#v for %q, got %#v", expected, line, args)
+		}
+	}
+}
+
+func TestSplitArgs_Unterminated(t *testing.T) {
+	for _, line := range []string{`--name 'John`, `--name "John`, `--name John\`} {
+		_, err := SplitArgs(line)
+		if err == nil {
+			t.Fatalf("Splitting %q should have failed", line)
+		}
+	}
+}
+
+func TestSplitArgs_QuotedValues(t *testing.T) {
+	type nameOptions struct {
+		Name  string   `goptions:"-n, --name"`
+		Files []string `goptions:"positional"`
+	}
+	tests := map[string]nameOptions{
+		`--name "John Doe" a`:   {Name: "John Doe", Files: []string{"a"}},
+		`--name 'John Doe'`:     {Name: "John Doe"},
+		`--name="John Doe" a b`: {Name: "John Doe", Files: []string{"a", "b"}},
+		`-n John\ Doe`:          {Name: "John Doe"},
+	}
+	for line, expected := range tests {
+		args, err := SplitArgs(line)
+		if err != nil {
+			t.Fatalf("Splitting %q failed: %s", line, err)
+		}
+		var options nameOptions
+		fs := NewFlagSet("goptions", &options)
+		err = fs.Parse(args)
+		if err != nil {
+			t.Fatalf("Parsing %q failed: %s", line, err)
+		}
+		if !reflect.DeepEqual(options, expected) {
+			t.Fatalf("Unexpected value for %q: %#v", line, options)
+		}
+	}
+}
+
+func TestParse_ArgsFromStdinWhenEmpty(t *testing.T) {
+	var options struct {
+		Force bool   `goptions:"-f, --force"`
+		Name  string `goptions:"--name"`
+	}
+
+	fs := NewFlagSet("goptions", &options)
+	fs.ArgsFromStdinWhenEmpty = true
+	fs.ArgsInput = strings.NewReader("--force --name 'John Doe'\n")
+	err := fs.Parse([]string{})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !options.Force || options.Name != "John Doe" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	options.Force, options.Name = false, ""
+	fs = NewFlagSet("goptions", &options)
+	fs.ArgsFromStdinWhenEmpty = true
+	fs.ArgsInput = strings.NewReader("--force")
+	err = fs.Parse([]string{"--name", "x"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Force || options.Name != "x" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
@@ -0,0 +1,69 @@
+package goptions
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// structValueParser parses comma-separated key=value pairs (e.g.
+// `src=/a,dst=/b`) into a struct. The keys are the names given in the
+// `goptions` tags of the struct's fields or the lower-cased field names.
+// Each value is parsed like the value of a flag of the field's type.
+func structValueParser(f *Flag, val string) (reflect.Value, error) {
+	t := f.value.Type()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	fields := structFieldsByKey(t)
+	r := reflect.New(t).Elem()
+	for _, pair := range strings.Split(val, ",") {
+		idx := strings.Index(pair, "=")
+		if idx < 0 {
+			return reflect.Value{}, fmt.Errorf("Invalid key/value pair %q for %s", pair, f.Name())
+		}
+		key := strings.TrimSpace(pair[:idx])
+		i, ok := fields[key]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("Unknown key %s for %s, must be one of: %s", key, f.Name(), strings.Join(sortedKeys(fields), ", "))
+		}
+		field := &Flag{
+			Short:      f.Short,
+			Long:       f.Long,
+			value:      r.Field(i),
+			optionMeta: make(map[string]interface{}),
+			flagSet:    f.flagSet,
+		}
+		err := field.setValue(pair[idx+1:])
+		if err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return r, nil
+}
+
+func structFieldsByKey(t reflect.Type) map[string]int {
+	r := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := strings.ToLower(field.Name)
+		if tag := strings.TrimSpace(field.Tag.Get("goptions")); tag != "" {
+			key = tag
+		}
+		r[key] = i
+	}
+	return r
+}
+
+func sortedKeys(m map[string]int) []string {
+	r := make([]string, 0, len(m))
+	for key := range m {
+		r = append(r, key)
+	}
+	sort.Strings(r)
+	return r
+}
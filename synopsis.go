@@ -0,0 +1,100 @@
+package goptions
+
+import (
+	"strings"
+)
+
+// Metavar returns the placeholder for the flag's value used in the
+// synopsis. It can be set with the `metavar` option and defaults to the
+// upper-cased name of the flag.
+func (f *Flag) Metavar() string {
+	if metavar, ok := f.optionMeta["metavar"].(string); ok {
+		return metavar
+	}
+	name := f.Long
+	if len(name) == 0 {
+		name = f.Short
+	}
+	if len(name) == 0 {
+		return "VALUE"
+	}
+	return strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// Synopsis returns the flag's part of the FlagSet's synopsis, e.g.
+// "[--name NAME]". It can be replaced with the `synopsis` option.
+func (f *Flag) Synopsis() string {
+	if synopsis, ok := f.optionMeta["synopsis"].(string); ok {
+		return synopsis
+	}
+	r := f.synopsisTerm()
+	if !f.Obligatory {
+		r = "[" + r + "]"
+	}
+	return r
+}
+
+// synopsisTerm returns the flag's part of the synopsis without the
+// brackets of optional flags.
+func (f *Flag) synopsisTerm() string {
+	if synopsis, ok := f.optionMeta["synopsis"].(string); ok {
+		return synopsis
+	}
+	r := f.Name()
+	if f.isPositional() {
+		r = f.Metavar()
+	} else if f.NeedsExtraValue() {
+		r += " " + f.Metavar()
+	}
+	if f.IsMulti() {
+		r += "..."
+	}
+	return r
+}
+
+// Synopsis returns a one-line summary of the FlagSet's usage, like
+// "name [-v] (--stdin | --file FILE) SRC [FILE...] <verb> [verb options]".
+// The flags of a MutexGroup are rendered as an alternation, in parentheses
+// if the group is obligatory and in brackets otherwise.
+func (fs *FlagSet) Synopsis() string {
+	parts := []string{fs.Name}
+	if fs.parent == nil {
+		parts[0] = fs.ProgramName()
+	}
+	mgs := fs.MutexGroups()
+	rendered := make(map[*Flag]bool)
+	for _, f := range fs.Flags {
+		if rendered[f] {
+			continue
+		}
+		if len(f.MutexGroups) == 0 || len(f.MutexGroups[0]) == 0 {
+			parts = append(parts, f.Synopsis())
+			continue
+		}
+		name := f.MutexGroups[0]
+		terms := make([]string, 0, len(mgs[name]))
+		for _, member := range mgs[name] {
+			if !rendered[member] {
+				rendered[member] = true
+				terms = append(terms, member.synopsisTerm())
+			}
+		}
+		if mgs[name].IsObligatory() {
+			parts = append(parts, "("+strings.Join(terms, " | ")+")")
+		} else {
+			parts = append(parts, "["+strings.Join(terms, " | ")+"]")
+		}
+	}
+	for _, f := range fs.positionals {
+		parts = append(parts, f.Synopsis())
+	}
+	if len(fs.Verbs) > 0 {
+		parts = append(parts, "<verb> [verb options]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Positionals returns the positional arguments of the FlagSet.
+func (fs *FlagSet) Positionals() []*Flag {
+	return fs.positionals
+}
@@ -0,0 +1,139 @@
+package goptions
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSynopsis_Positionals(t *testing.T) {
+	var options struct {
+		Verbose bool     `goptions:"-v"`
+		Name    string   `goptions:"--name, metavar='NAME'"`
+		Src     string   `goptions:"positional, obligatory"`
+		Files   []string `goptions:"positional, metavar='FILE'"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	expected := "goptions [-v] [--name NAME] SRC [FILE...]"
+	if fs.Synopsis() != expected {
+		t.Fatalf("Expected %q, got %q", expected, fs.Synopsis())
+	}
+
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	if !strings.HasPrefix(buf.String(), "Usage: goptions [global options] SRC [FILE...] \n") {
+		t.Fatalf("Positionals not rendered in help: %q", buf.String())
+	}
+}
+
+func TestSynopsis_Custom(t *testing.T) {
+	var options struct {
+		From int `goptions:"--from, synopsis='[--from A --to B]'"`
+		To   int `goptions:"--to"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	expected := "goptions [--from A --to B] [--to TO]"
+	if fs.Synopsis() != expected {
+		t.Fatalf("Expected %q, got %q", expected, fs.Synopsis())
+	}
+}
+
+func TestSynopsis_MutexGroups(t *testing.T) {
+	var options struct {
+		Verbose bool     `goptions:"-v"`
+		Stdin   bool     `goptions:"--stdin, mutexgroup='input', obligatory"`
+		File    string   `goptions:"--file, mutexgroup='input'"`
+		JSON    bool     `goptions:"--json, mutexgroup='format'"`
+		YAML    bool     `goptions:"--yaml, mutexgroup='format'"`
+		Tags    []string `goptions:"--tag"`
+	}
+	fs := NewFlagSet("goptions", &options)
+
+	expected := "goptions [-v] (--stdin | --file FILE) [--json | --yaml] [--tag TAG...]"
+	if fs.Synopsis() != expected {
+		t.Fatalf("Expected %q, got %q", expected, fs.Synopsis())
+	}
+}
+
+func TestSynopsis_ProgramName(t *testing.T) {
+	var options struct {
+		Verbose bool `goptions:"-v"`
+	}
+	fs := NewFlagSet("/usr/local/bin/tool", &options)
+
+	if fs.Synopsis() != "tool [-v]" {
+		t.Fatalf("Unexpected synopsis: %q", fs.Synopsis())
+	}
+	var buf bytes.Buffer
+	fs.PrintHelp(&buf)
+	if !strings.HasPrefix(buf.String(), "Usage: tool [global options]") {
+		t.Fatalf("Unexpected help: %q", buf.String())
+	}
+
+	fs.FullProgramName = true
+	if fs.Synopsis() != "/usr/local/bin/tool [-v]" {
+		t.Fatalf("Unexpected synopsis: %q", fs.Synopsis())
+	}
+}
+
+func TestParse_FullProgramName(t *testing.T) {
+	var options struct {
+		Verbose bool `goptions:"-v"`
+	}
+	defer func(args []string) {
+		os.Args = args
+		FullProgramName = false
+		globalFlagSet = nil
+	}(os.Args)
+	os.Args = []string{"/usr/local/bin/tool", "-v"}
+
+	err := Parse(&options)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if name := globalFlagSet.ProgramName(); name != "tool" {
+		t.Fatalf("Unexpected program name: %q", name)
+	}
+
+	FullProgramName = true
+	err = Parse(&options)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if name := globalFlagSet.ProgramName(); name != "/usr/local/bin/tool" {
+		t.Fatalf("Unexpected program name: %q", name)
+	}
+}
+
+func TestParse_Positionals(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Verbose bool     `goptions:"-v"`
+		Src     string   `goptions:"positional, obligatory"`
+		Files   []string `goptions:"positional"`
+	}
+
+	args = []string{"-v", "a", "b", "c"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if !(options.Verbose &&
+		options.Src == "a" &&
+		len(options.Files) == 2) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"-v"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "SRC must be specified" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
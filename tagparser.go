@@ -9,7 +9,7 @@ import (
 
 const (
 	_LONG_FLAG_REGEXP     = `--[[:word:]-]+`
-	_SHORT_FLAG_REGEXP    = `-[[:alnum:]]`
+	_SHORT_FLAG_REGEXP    = `-[\pL\pN]`
 	_QUOTED_STRING_REGEXP = `'((?:\\'|[^\\'])+)'`
 	_OPTION_REGEXP        = `([[:word:]-]+)(?:=` + _QUOTED_STRING_REGEXP + `)?`
 )
@@ -19,10 +19,15 @@ var (
 )
 
 func parseStructField(fieldValue reflect.Value, tag string) (*Flag, error) {
+	return parseTag(fieldValue, tag, false)
+}
+
+// parseTag parses the tag of a struct field. If lenient is set, unknown
+// options are ignored instead of returning an error.
+func parseTag(fieldValue reflect.Value, tag string, lenient bool) (*Flag, error) {
 	f := &Flag{
-		value:        fieldValue,
-		DefaultValue: fieldValue.Interface(),
-		optionMeta:   make(map[string]interface{}),
+		value:      fieldValue,
+		optionMeta: make(map[string]interface{}),
 	}
 	for {
 		tag = strings.TrimSpace(tag)
@@ -53,16 +58,52 @@ func parseStructField(fieldValue reflect.Value, tag string) (*Flag, error) {
 			}
 			optionmap := optionMapForType(fieldValue.Type())
 			opf, ok := optionmap[option]
-			if !ok {
+			if !ok && !lenient {
 				return nil, fmt.Errorf("Unknown option %s", option)
 			}
-			err := opf(f, option, value)
-			if err != nil {
-				return nil, fmt.Errorf("Option %s invalid: %s", option, err)
+			if ok {
+				err := opf(f, option, value)
+				if err != nil {
+					return nil, fmt.Errorf("Option %s invalid: %s", option, err)
+				}
 			}
 		}
 		// Keep remainder
 		tag = tag[idx[1]:]
 	}
+	if _, ok := f.optionMeta["default"]; ok && f.Obligatory {
+		return nil, fmt.Errorf("Obligatory flag %s cannot have a default value", f.Name())
+	}
+	// An absent bool is just false, so it can only be obligatory as part
+	// of a mutexgroup or if it can be negated explicitly.
+	if f.Obligatory && f.value.Type() == reflect.TypeOf(false) &&
+		len(f.MutexGroups) == 0 && len(f.NegatedLongs()) == 0 {
+		return nil, fmt.Errorf("Bool flag %s cannot be obligatory, use a non-bool type if a value is required", f.Name())
+	}
+	err := f.initValue()
+	if err != nil {
+		return nil, err
+	}
 	return f, nil
 }
+
+// initValue records the initial value of the flag and applies its default.
+func (f *Flag) initValue() error {
+	f.DefaultValue = f.value.Interface()
+	f.source = SourceUnset
+	if !f.value.IsZero() {
+		f.source = SourceDefault
+	}
+	if def, ok := f.interpolatedDefault(); ok {
+		// Applied by Parse() once the referenced flags are known
+		f.DefaultValue = def
+	} else if def, ok := f.optionMeta["default"].(string); ok {
+		err := f.setValue(unescapeReferences(def))
+		if err != nil {
+			return fmt.Errorf("Invalid default value for %s: %s", f.Name(), err)
+		}
+		f.DefaultValue = f.value.Interface()
+		f.source = SourceDefault
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package goptions
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// A TransformFunc modifies the value of a flag before it is parsed.
+type TransformFunc func(val string) (string, error)
+
+var (
+	transformMap = map[string]TransformFunc{
+		"trim":    trimTransform,
+		"lower":   lowerTransform,
+		"upper":   upperTransform,
+		"abspath": filepath.Abs,
+	}
+)
+
+// RegisterTransform makes a TransformFunc available to the `transform`
+// option under the given name. Transforms have to be registered before
+// the FlagSets using them are created.
+func RegisterTransform(name string, fn TransformFunc) {
+	transformMap[name] = fn
+}
+
+func (f *Flag) transform(s string) (string, error) {
+	transforms, _ := f.optionMeta["transforms"].([]TransformFunc)
+	for _, fn := range transforms {
+		var err error
+		s, err = fn(s)
+		if err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+func trimTransform(val string) (string, error) {
+	return strings.TrimSpace(val), nil
+}
+
+func lowerTransform(val string) (string, error) {
+	return strings.ToLower(val), nil
+}
+
+func upperTransform(val string) (string, error) {
+	return strings.ToUpper(val), nil
+}
@@ -0,0 +1,87 @@
+package goptions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Name string `goptions:"--name, transform='trim,lower'"`
+	}
+
+	args = []string{"--name", "  SomeName "}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Name != "somename" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestTransform_AbsPath(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options struct {
+		Config string   `goptions:"--config, transform='abspath'"`
+		Paths  []string `goptions:"--path, transform='abspath'"`
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %s", err)
+	}
+	args = []string{"--config", "conf/app.toml", "--path", "/tmp/../var", "--path", "."}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Config != filepath.Join(wd, "conf", "app.toml") ||
+		!reflect.DeepEqual(options.Paths, []string{"/var", wd}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+}
+
+func TestTransform_Registered(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	RegisterTransform("nodash", func(val string) (string, error) {
+		if strings.HasPrefix(val, "-") {
+			return val, fmt.Errorf("Must not start with a dash")
+		}
+		return strings.Replace(val, "-", "_", -1), nil
+	})
+	defer delete(transformMap, "nodash")
+	var options struct {
+		Name string `goptions:"--name, transform='nodash'"`
+	}
+
+	args = []string{"--name", "some-name"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Name != "some_name" {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	args = []string{"--name=-name"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || !strings.Contains(err.Error(), "--name") {
+		t.Fatalf("Expected error naming --name, got: %v", err)
+	}
+}
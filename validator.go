@@ -0,0 +1,56 @@
+package goptions
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var (
+	validatorMap = map[string]func(reflect.Value) error{}
+)
+
+// RegisterValidator makes a validation function available to the
+// `validate` option under the given name. The function is called with
+// each value set for the flag, i.e. with the elements of slices, and
+// Parse() fails if it returns an error. Validators have to be registered
+// before the FlagSets using them are created.
+func RegisterValidator(name string, fn func(reflect.Value) error) {
+	validatorMap[name] = fn
+}
+
+// A Validator validates the state of an options struct after parsing.
+// If the (pointer to the) struct of a FlagSet or of a verb implements
+// Validator, Parse() calls Validate() after all flags have been set and
+// checked and returns its error.
+type Validator interface {
+	Validate() error
+}
+
+func (fs *FlagSet) validate() error {
+	if !fs.structValue.IsValid() || !fs.structValue.CanAddr() {
+		return nil
+	}
+	if v, ok := fs.structValue.Addr().Interface().(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// checkValidators runs the validators of the `validate` option on the
+// value which has been set last.
+func (f *Flag) checkValidators() error {
+	validators, _ := f.optionMeta["validators"].([]func(reflect.Value) error)
+	if len(validators) == 0 {
+		return nil
+	}
+	v := f.value
+	if v.Kind() == reflect.Slice && v.Len() > 0 {
+		v = v.Index(v.Len() - 1)
+	}
+	for _, fn := range validators {
+		if err := fn(v); err != nil {
+			return fmt.Errorf("Invalid value for %s: %s", f.Name(), err)
+		}
+	}
+	return nil
+}
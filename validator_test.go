@@ -0,0 +1,92 @@
+package goptions
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type rangeOptions struct {
+	From int `goptions:"--from"`
+	To   int `goptions:"--to"`
+
+	Verbs
+	Print printOptions `goptions:"print"`
+}
+
+func (o *rangeOptions) Validate() error {
+	if o.From > o.To {
+		return fmt.Errorf("--from must not be greater than --to")
+	}
+	return nil
+}
+
+type printOptions struct {
+	Width int `goptions:"--width"`
+}
+
+func (o *printOptions) Validate() error {
+	if o.Width < 0 {
+		return fmt.Errorf("--width must not be negative")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	var args []string
+	var err error
+	var fs *FlagSet
+	var options rangeOptions
+
+	args = []string{"--from", "1", "--to", "5", "print", "--width", "10"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+
+	options = rangeOptions{}
+	args = []string{"--from", "5", "--to", "1"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil || err.Error() != "--from must not be greater than --to" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	options = rangeOptions{}
+	args = []string{"print", "--width", "-1"}
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse(args)
+	if err == nil {
+		t.Fatalf("Parsing should have failed")
+	}
+}
+
+func TestRegisterValidator(t *testing.T) {
+	RegisterValidator("odd", func(v reflect.Value) error {
+		if v.Int()%2 == 0 {
+			return fmt.Errorf("%d is even", v.Int())
+		}
+		return nil
+	})
+	defer delete(validatorMap, "odd")
+	var options struct {
+		Port  int   `goptions:"--port, validate='odd'"`
+		Ports []int `goptions:"--ports, validate='odd'"`
+	}
+
+	fs := NewFlagSet("goptions", &options)
+	err := fs.Parse([]string{"--port", "3", "--ports", "5", "--ports", "7"})
+	if err != nil {
+		t.Fatalf("Parsing failed: %s", err)
+	}
+	if options.Port != 3 || !reflect.DeepEqual(options.Ports, []int{5, 7}) {
+		t.Fatalf("Unexpected value: %#v", options)
+	}
+
+	fs = NewFlagSet("goptions", &options)
+	err = fs.Parse([]string{"--ports", "5", "--ports", "8"})
+	if err == nil || err.Error() != "Invalid value for --ports: 8 is even" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
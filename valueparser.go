@@ -2,30 +2,91 @@ package goptions
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type valueParser func(f *Flag, val string) (reflect.Value, error)
 
 var (
 	parserMap = map[reflect.Type]valueParser{
-		reflect.TypeOf(new(bool)).Elem():     boolValueParser,
-		reflect.TypeOf(new(string)).Elem():   stringValueParser,
-		reflect.TypeOf(new(int)).Elem():      intValueParser,
-		reflect.TypeOf(new(Help)).Elem():     helpValueParser,
-		reflect.TypeOf(new(*os.File)).Elem(): fileValueParser,
+		reflect.TypeOf(new(bool)).Elem():          boolValueParser,
+		reflect.TypeOf(new(string)).Elem():        stringValueParser,
+		reflect.TypeOf(new(int)).Elem():           intValueParser,
+		reflect.TypeOf(new(float64)).Elem():       floatValueParser,
+		reflect.TypeOf(new(Help)).Elem():          markerValueParser,
+		reflect.TypeOf(new(HelpAll)).Elem():       markerValueParser,
+		reflect.TypeOf(new(Version)).Elem():       markerValueParser,
+		reflect.TypeOf(new(Completion)).Elem():    markerValueParser,
+		reflect.TypeOf(new(Counter)).Elem():       counterValueParser,
+		reflect.TypeOf(new(*os.File)).Elem():      fileValueParser,
+		reflect.TypeOf(new(time.Duration)).Elem(): durationValueParser,
+		reflect.TypeOf(new(time.Time)).Elem():     timeValueParser,
+		reflect.TypeOf(new(*big.Int)).Elem():      bigIntValueParser,
+		reflect.TypeOf(new(*big.Float)).Elem():    bigFloatValueParser,
 	}
 )
 
-func (f *Flag) setValue(s string) (err error) {
+func (f *Flag) setValue(s string) error {
+	values := []string{s}
+	if delim, ok := f.optionMeta["delim"].(string); ok {
+		values = splitEscaped(s, delim)
+	}
+	for _, value := range values {
+		err := f.assignValue(value)
+		if err != nil {
+			return err
+		}
+		err = f.checkValidators()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitEscaped splits s at delim unless it is preceded by a backslash, in
+// which case the backslash is dropped.
+func splitEscaped(s, delim string) []string {
+	r := make([]string, 0, 1)
+	var cur []byte
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], `\`+delim):
+			cur = append(cur, delim...)
+			i += 1 + len(delim)
+		case strings.HasPrefix(s[i:], delim):
+			r = append(r, string(cur))
+			cur = cur[0:0]
+			i += len(delim)
+		default:
+			cur = append(cur, s[i])
+			i++
+		}
+	}
+	return append(r, string(cur))
+}
+
+func (f *Flag) assignValue(s string) (err error) {
 	defer func() {
 		if x := recover(); x != nil {
 			err = x.(error)
 			return
 		}
 	}()
+	s, err = f.transform(s)
+	if err != nil {
+		return fmt.Errorf("Invalid value for %s: %s", f.Name(), err)
+	}
+	s, err = f.checkChoices(s)
+	if err != nil {
+		return err
+	}
 	if _, ok := f.value.Interface().(Marshaler); ok {
 		newval := reflect.New(f.value.Type()).Elem()
 		if newval.Kind() == reflect.Ptr {
@@ -36,11 +97,31 @@ func (f *Flag) setValue(s string) (err error) {
 		f.value.Set(newval)
 		return err
 	}
+	if f.value.Kind() == reflect.Slice {
+		if newval, m, ok := newMarshaler(f.value.Type().Elem()); ok {
+			err := m.MarshalGoption(s)
+			if err != nil {
+				return err
+			}
+			f.value.Set(reflect.Append(f.value, newval))
+			return nil
+		}
+	}
+	if f.value.Kind() == reflect.Map {
+		return f.setMapEntry(s)
+	}
 	vtype := f.value.Type()
 	if f.value.Kind() == reflect.Slice {
 		vtype = f.value.Type().Elem()
 	}
-	if parser, ok := parserMap[vtype]; ok {
+	parser, ok := parserMap[vtype]
+	if !ok && vtype.Kind() == reflect.Struct {
+		parser, ok = structValueParser, true
+	}
+	if !ok && vtype.Kind() == reflect.Interface {
+		parser, ok = schemeValueParser(vtype)
+	}
+	if ok {
 		val, err := parser(f, s)
 		if err != nil {
 			return err
@@ -54,11 +135,77 @@ func (f *Flag) setValue(s string) (err error) {
 	} else {
 		return fmt.Errorf("Unsupported flag type: %s", f.value.Type().Name())
 	}
-	panic("Invalid execution path")
+}
+
+// setMapEntry parses s as a key=value pair and adds it to the map of the
+// flag. Keys and values are parsed according to the map's types.
+func (f *Flag) setMapEntry(s string) error {
+	idx := strings.Index(s, "=")
+	if idx < 0 {
+		return fmt.Errorf("Invalid entry %q for %s, must be key=value", s, f.Name())
+	}
+	t := f.value.Type()
+	keyParser, ok := parserMap[t.Key()]
+	if !ok {
+		return fmt.Errorf("Unsupported flag type: %s", t)
+	}
+	valueParser, ok := parserMap[t.Elem()]
+	if !ok {
+		return fmt.Errorf("Unsupported flag type: %s", t)
+	}
+	key, err := keyParser(f, s[:idx])
+	if err != nil {
+		return err
+	}
+	val, err := valueParser(f, s[idx+1:])
+	if err != nil {
+		return err
+	}
+	if f.value.IsNil() {
+		f.value.Set(reflect.MakeMap(t))
+	}
+	f.value.SetMapIndex(key, val)
+	return nil
+}
+
+// newMarshaler returns a new value of type t and its Marshaler if t or a
+// pointer to t implements Marshaler. Pointers are allocated.
+func newMarshaler(t reflect.Type) (reflect.Value, Marshaler, bool) {
+	newval := reflect.New(t).Elem()
+	if t.Kind() == reflect.Ptr {
+		newval.Set(reflect.New(t.Elem()))
+	}
+	if m, ok := newval.Interface().(Marshaler); ok {
+		return newval, m, true
+	}
+	if m, ok := newval.Addr().Interface().(Marshaler); ok {
+		return newval, m, true
+	}
+	return reflect.Value{}, nil, false
+}
+
+// checkChoices returns the canonical spelling of s if the flag restricts
+// its values to a set of choices.
+func (f *Flag) checkChoices(s string) (string, error) {
+	choices := f.Choices()
+	if choices == nil {
+		return s, nil
+	}
+	ci, _ := f.optionMeta["choices_ci"].(bool)
+	for _, choice := range choices {
+		if s == choice || (ci && strings.EqualFold(s, choice)) {
+			return choice, nil
+		}
+	}
+	return s, fmt.Errorf("Invalid value %q for %s, must be one of: %s", s, f.Name(), strings.Join(choices, ", "))
 }
 
 func boolValueParser(f *Flag, val string) (reflect.Value, error) {
-	return reflect.ValueOf(true), nil
+	if val == "" {
+		return reflect.ValueOf(true), nil
+	}
+	boolval, err := strconv.ParseBool(val)
+	return reflect.ValueOf(boolval), err
 }
 
 func stringValueParser(f *Flag, val string) (reflect.Value, error) {
@@ -70,6 +217,96 @@ func intValueParser(f *Flag, val string) (reflect.Value, error) {
 	return reflect.ValueOf(int(intval)), err
 }
 
+func floatValueParser(f *Flag, val string) (reflect.Value, error) {
+	normalized, err := f.normalizeDecimal(val)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	floatval, err := strconv.ParseFloat(normalized, 64)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if _, ok := f.optionMeta["allow_nonfinite"]; !ok && (math.IsNaN(floatval) || math.IsInf(floatval, 0)) {
+		return reflect.Value{}, fmt.Errorf("Non-finite value %q for %s", val, f.Name())
+	}
+	return reflect.ValueOf(floatval), nil
+}
+
+// normalizeDecimal converts a number with a decimal comma and dots as
+// thousands separators (e.g. "1.234,5") into "1234.5" if the FlagSet has
+// DecimalComma set. Dots are only accepted as separators of groups of three
+// digits before the comma, so that e.g. "3.14" is rejected instead of being
+// read as 314.
+func (f *Flag) normalizeDecimal(val string) (string, error) {
+	if f.flagSet == nil || !f.flagSet.root().DecimalComma {
+		return val, nil
+	}
+	integer, fraction := val, ""
+	if idx := strings.Index(val, ","); idx >= 0 {
+		integer, fraction = val[:idx], "."+val[idx+1:]
+	}
+	if strings.ContainsAny(strings.TrimPrefix(fraction, "."), ".,") {
+		return "", fmt.Errorf("Invalid decimal %q for %s", val, f.Name())
+	}
+	if strings.Contains(integer, ".") {
+		groups := strings.Split(strings.TrimLeft(integer, "+-"), ".")
+		for i, group := range groups {
+			if (i == 0 && (len(group) == 0 || len(group) > 3)) || (i > 0 && len(group) != 3) ||
+				strings.Trim(group, "0123456789") != "" {
+				return "", fmt.Errorf("Invalid decimal %q for %s, dots are only allowed as thousands separators", val, f.Name())
+			}
+		}
+		integer = strings.Replace(integer, ".", "", -1)
+	}
+	return integer + fraction, nil
+}
+
+func durationValueParser(f *Flag, val string) (reflect.Value, error) {
+	if unit, ok := f.optionMeta["duration_unit"].(string); ok {
+		if _, err := strconv.ParseFloat(val, 64); err == nil {
+			val += unit
+		}
+	}
+	d, err := time.ParseDuration(val)
+	return reflect.ValueOf(d), err
+}
+
+func timeValueParser(f *Flag, val string) (reflect.Value, error) {
+	layouts, ok := f.optionMeta["time_layouts"].([]string)
+	if !ok {
+		layouts = []string{time.RFC3339}
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, val); err == nil {
+			return reflect.ValueOf(t), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("Invalid time %q for %s, must match one of: %s", val, f.Name(), strings.Join(layouts, ", "))
+}
+
+func bigIntValueParser(f *Flag, val string) (reflect.Value, error) {
+	i, ok := new(big.Int).SetString(val, 0)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("Invalid integer %q for %s", val, f.Name())
+	}
+	return reflect.ValueOf(i), nil
+}
+
+func bigFloatValueParser(f *Flag, val string) (reflect.Value, error) {
+	normalized, err := f.normalizeDecimal(val)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	x, ok := new(big.Float).SetString(normalized)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("Invalid number %q for %s", val, f.Name())
+	}
+	if _, ok := f.optionMeta["allow_nonfinite"]; !ok && x.IsInf() {
+		return reflect.Value{}, fmt.Errorf("Non-finite value %q for %s", val, f.Name())
+	}
+	return reflect.ValueOf(x), nil
+}
+
 func fileValueParser(f *Flag, val string) (reflect.Value, error) {
 	mode := 0
 	if v, ok := f.optionMeta["file_mode"].(int); ok {
@@ -86,12 +323,31 @@ func fileValueParser(f *Flag, val string) (reflect.Value, error) {
 		if v, ok := f.optionMeta["file_perm"].(uint32); ok {
 			perm = v
 		}
-		f, e := os.OpenFile(val, mode, os.FileMode(perm))
-		return reflect.ValueOf(f), e
+		if f.flagSet != nil && f.flagSet.isValidateOnly() {
+			f.flagSet.skipAction("Open %s for %s", val, f.Name())
+			return reflect.Zero(f.value.Type()), nil
+		}
+		file, err := os.OpenFile(val, mode, os.FileMode(perm))
+		if err != nil {
+			return reflect.ValueOf(file), err
+		}
+		if f.flagSet != nil {
+			root := f.flagSet.root()
+			root.closers = append(root.closers, file)
+		}
+		return reflect.ValueOf(file), nil
 	}
 	panic("Invalid execution path")
 }
 
-func helpValueParser(f *Flag, val string) (reflect.Value, error) {
-	return reflect.Value{}, ErrHelpRequest
+func counterValueParser(f *Flag, val string) (reflect.Value, error) {
+	if val == "" {
+		return reflect.ValueOf(f.value.Interface().(Counter) + 1), nil
+	}
+	intval, err := strconv.ParseInt(val, 10, 64)
+	return reflect.ValueOf(Counter(intval)), err
+}
+
+func markerValueParser(f *Flag, val string) (reflect.Value, error) {
+	return reflect.Value{}, markerErrors[f.value.Type()]
 }